@@ -0,0 +1,19 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"github.com/rogpeppe/go-internal/testscript"
+	"os"
+	"testing"
+)
+
+func TestIsolatedShell(t *testing.T) {
+	testscript.Run(t, testscript.Params{
+		Dir: "testdata/isolated_shell",
+		Setup: func(env *testscript.Env) error {
+			env.Setenv("HOME", os.TempDir())
+			return nil
+		},
+	})
+}
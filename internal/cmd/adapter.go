@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
+	"github.com/hpcsc/kz/internal/shell"
 	"github.com/urfave/cli/v2"
+	"io"
+	"strings"
 )
 
 // an adapter function that adapt a no-arguments function to CLI action handler
@@ -14,12 +18,12 @@ func noArgumentsAction(f func() error) func(ctx *cli.Context) error {
 
 func oneArgumentsAction(f func(string) error, validationMsg string) func(ctx *cli.Context) error {
 	return func(ctx *cli.Context) error {
-		query := ctx.Args().First()
-		if len(query) == 0 {
+		arg := ctx.Args().First()
+		if len(arg) == 0 {
 			return fmt.Errorf(validationMsg)
 		}
 
-		return f(query)
+		return f(arg)
 	}
 }
 
@@ -33,3 +37,152 @@ func sliceArgumentsAction(f func([]string) error, validationMsg string) func(ctx
 		return f(args)
 	}
 }
+
+// an adapter function that calls withArgument when the first argument is provided and withoutArgument otherwise
+func optionalArgumentAction(withArgument func(string) error, withoutArgument func() error) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		args, err := argumentsOf(ctx, 1)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return withoutArgument()
+		}
+
+		return withArgument(args[0])
+	}
+}
+
+// an adapter function similar to optionalArgumentAction that also passes switch options from flags
+func switchAction(withArgument func(switchOptions, string) error, withoutArgument func(switchOptions) error) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		args, err := argumentsOf(ctx, 1)
+		if err != nil {
+			return err
+		}
+
+		opts := switchOptionsFrom(ctx)
+		if len(args) == 0 {
+			return withoutArgument(opts)
+		}
+
+		return withArgument(opts, args[0])
+	}
+}
+
+// an adapter function that passes the validated output format to f
+func outputAction(f func(string) error) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		if _, err := argumentsOf(ctx, 0); err != nil {
+			return err
+		}
+
+		output := outputFrom(ctx)
+		if err := validateOutput(output); err != nil {
+			return err
+		}
+
+		return f(output)
+	}
+}
+
+// an adapter function that passes the validated output format and the first argument, which can be empty, to f
+func outputWithOptionalArgumentAction(f func(string, string) error) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		args, err := argumentsOf(ctx, 1)
+		if err != nil {
+			return err
+		}
+
+		output := outputFrom(ctx)
+		if err := validateOutput(output); err != nil {
+			return err
+		}
+
+		var query string
+		if len(args) > 0 {
+			query = args[0]
+		}
+
+		return f(output, query)
+	}
+}
+
+// an adapter function that resolves the shell from `--shell` flag, falling back to the shell from shell integration or environment
+func shellAction(f func(shell.Shell) error) func(ctx *cli.Context) error {
+	return func(ctx *cli.Context) error {
+		if !ctx.IsSet("shell") {
+			if sh, ok := integratedShell(); ok {
+				return f(sh)
+			}
+
+			return f(shell.Detect())
+		}
+
+		sh, err := shell.Parse(ctx.String("shell"))
+		if err != nil {
+			return err
+		}
+
+		return f(sh)
+	}
+}
+
+// argumentsOf returns positional arguments of the command, or an error when there are more than max of them.
+// urfave/cli stops parsing flags at the first positional argument, so flags of the command given after positional arguments,
+// .e.g. `kz prod --for 15m`, are parsed here and set on the command as if they were given before them
+func argumentsOf(ctx *cli.Context, max int) ([]string, error) {
+	fs := flag.NewFlagSet(ctx.Command.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, f := range ctx.Command.Flags {
+		// configuration location is already used by the time actions run
+		if f.Names()[0] == "config" {
+			continue
+		}
+
+		if err := f.Apply(fs); err != nil {
+			return nil, err
+		}
+	}
+
+	var positional []string
+	remaining := ctx.Args().Slice()
+	for len(remaining) > 0 {
+		if !isFlag(remaining[0]) {
+			positional = append(positional, remaining[0])
+			remaining = remaining[1:]
+			continue
+		}
+
+		if err := fs.Parse(remaining); err != nil {
+			return nil, fmt.Errorf("%v, flags of %s are only accepted before its arguments", err, ctx.Command.HelpName)
+		}
+		remaining = fs.Args()
+		if len(remaining) > 0 {
+			positional = append(positional, remaining[0])
+			remaining = remaining[1:]
+		}
+	}
+
+	if len(positional) > max {
+		return nil, fmt.Errorf("unexpected argument(s) %s, %s accepts at most %d argument(s)", strings.Join(positional[max:], " "), ctx.Command.HelpName, max)
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if setErr := ctx.Set(f.Name, f.Value.String()); setErr != nil && err == nil {
+			err = setErr
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return positional, nil
+}
+
+// isFlag reports whether the argument is a flag, a single dash is an argument, .e.g. current context in `kz - ns1`
+func isFlag(arg string) bool {
+	return len(arg) > 1 && strings.HasPrefix(arg, "-")
+}
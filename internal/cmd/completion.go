@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/shell"
+	"github.com/urfave/cli/v2"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+func newCompletionSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "completion",
+		Usage:     "print completion script for the given shell",
+		ArgsUsage: "<bash|zsh|fish>",
+		Description: `Completion is already registered by shell integration (see kz init), use this command when shell integration is not used.
+
+bash: add source <(kz completion bash) to ~/.bashrc
+zsh: add source <(kz completion zsh) to ~/.zshrc
+fish: add kz completion fish | source to ~/.config/fish/config.fish`,
+		Action: oneArgumentsAction(printCompletion, "shell is required"),
+	}
+}
+
+func printCompletion(name string) error {
+	sh, err := shell.Parse(name)
+	if err != nil {
+		return err
+	}
+
+	script, err := shell.Completion(sh)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(script)
+	return nil
+}
+
+// completeContextAndNamespace suggests subcommands and contexts for the first argument of root command,
+// and namespaces for the second argument
+func completeContextAndNamespace(ctx *cli.Context) {
+	withFlagCompletion(ctx, func(cfg *config.Config) {
+		switch ctx.NArg() {
+		case 0:
+			cli.DefaultCompleteWithFlags(ctx.Command)(ctx)
+			printCandidates(ctx, cfg.ContextsByFrecency(time.Now()))
+		case 1:
+			printCandidates(ctx, cfg.NamespacesByFrecency(time.Now()))
+		}
+	})
+}
+
+func completeContext(ctx *cli.Context) {
+	withFlagCompletion(ctx, func(cfg *config.Config) {
+		if ctx.NArg() == 0 {
+			cli.DefaultCompleteWithFlags(ctx.Command)(ctx)
+			printCandidates(ctx, cfg.ContextsByFrecency(time.Now()))
+		}
+	})
+}
+
+func completeNamespace(ctx *cli.Context) {
+	withFlagCompletion(ctx, func(cfg *config.Config) {
+		if ctx.NArg() == 0 {
+			cli.DefaultCompleteWithFlags(ctx.Command)(ctx)
+			printCandidates(ctx, cfg.NamespacesByFrecency(time.Now()))
+		}
+	})
+}
+
+// completeSelectors suggests tags and contexts that are not in arguments yet
+func completeSelectors(ctx *cli.Context) {
+	withFlagCompletion(ctx, func(cfg *config.Config) {
+		var candidates []string
+		for tag := range cfg.Tags {
+			candidates = append(candidates, "@"+tag)
+		}
+		slices.Sort(candidates)
+		candidates = append(candidates, cfg.ContextsByFrecency(time.Now())...)
+
+		var remaining []string
+		for _, c := range candidates {
+			if !slices.Contains(ctx.Args().Slice(), c) {
+				remaining = append(remaining, c)
+			}
+		}
+		printCandidates(ctx, remaining)
+	})
+}
+
+// completeNamespacesToDelete suggests tracked namespaces that are not in arguments yet
+func completeNamespacesToDelete(ctx *cli.Context) {
+	withFlagCompletion(ctx, func(cfg *config.Config) {
+		var candidates []string
+		for _, n := range cfg.NamespacesByFrecency(time.Now()) {
+			if !slices.Contains(ctx.Args().Slice(), n) {
+				candidates = append(candidates, n)
+			}
+		}
+		printCandidates(ctx, candidates)
+	})
+}
+
+// withFlagCompletion falls back to default flag completion when a flag is being completed
+func withFlagCompletion(ctx *cli.Context, complete func(cfg *config.Config)) {
+	if len(os.Args) > 2 && strings.HasPrefix(os.Args[len(os.Args)-2], "-") {
+		cli.DefaultCompleteWithFlags(ctx.Command)(ctx)
+		return
+	}
+
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return
+	}
+
+	complete(cfg)
+}
+
+func printCandidates(ctx *cli.Context, candidates []string) {
+	// zsh completion expects `value:description`, same as default completion of urfave/cli
+	isZsh := strings.HasSuffix(os.Getenv("SHELL"), "zsh")
+	for _, c := range candidates {
+		if isZsh {
+			c = strings.ReplaceAll(c, ":", `\:`)
+		}
+		fmt.Fprintln(ctx.App.Writer, c)
+	}
+}
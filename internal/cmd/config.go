@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"os/exec"
+	"slices"
+)
+
+func newConfigSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "commands to work with kz configuration",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "show",
+				Usage: "show effective configuration merged from system, team and user configuration files",
+				Description: `Configuration files are merged from lowest to highest precedence: system (/etc/kz/config.yml or KZ_SYSTEM_CONFIG),
+team (KZ_TEAM_CONFIG) and user (~/.config/kz/config.yml, KZ_CONFIG or --config).
+Mappings are merged key by key, lists are appended and other values are replaced. Values tagged with !replace replace values from lower layers as a whole.`,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "origin",
+						Usage: "annotate every value with the configuration file it comes from",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					return showConfig(ctx.Bool("origin"))
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "print the effective value of a configuration key",
+				ArgsUsage: "<key>",
+				Description: `Keys are dot-separated paths of mapping keys and list indexes, e.g. protectedMaxDuration, tags.prod or hooks.0.pre.
+Lists and mappings are printed as yaml.`,
+				Action: oneArgumentsAction(getConfig, "configuration key is required"),
+			},
+			{
+				Name:      "set",
+				Usage:     "set the value of a configuration key in user configuration file",
+				ArgsUsage: "<key> <value>",
+				Description: `Keys are dot-separated paths of mapping keys and list indexes, e.g. protectedMaxDuration, tags.prod or hooks.0.pre.
+Values are parsed as yaml so that lists and mappings can be set, e.g. kz config set protected '["*prod*"]'.
+Comments and ordering of the configuration file are kept.`,
+				Action: func(ctx *cli.Context) error {
+					if ctx.Args().Len() != 2 {
+						return errors.New("configuration key and value are required")
+					}
+
+					return setConfig(ctx.Args().Get(0), ctx.Args().Get(1))
+				},
+			},
+			{
+				Name:  "edit",
+				Usage: "edit user configuration file in $EDITOR",
+				Description: `A copy of the configuration file is opened in $EDITOR, or vi when it's not set.
+The copy is validated before replacing the configuration file, invalid changes are kept in the copy.`,
+				Action: noArgumentsAction(editConfig),
+			},
+			{
+				Name:   "validate",
+				Usage:  "check configuration files for unknown keys, invalid patterns and references to contexts that don't exist",
+				Action: noArgumentsAction(validateConfig),
+			},
+		},
+	}
+}
+
+func showConfig(withOrigin bool) error {
+	layers, err := config.Layers()
+	if err != nil {
+		return err
+	}
+
+	if withOrigin {
+		for _, l := range layers {
+			if _, err := os.Stat(l.Location); err != nil {
+				fmt.Printf("# %s: %s (not found)\n", l.Name, l.Location)
+				continue
+			}
+			fmt.Printf("# %s: %s\n", l.Name, l.Location)
+		}
+	}
+
+	merged, err := config.MergeLayers(layers, withOrigin)
+	if err != nil || merged == nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	defer encoder.Close()
+	return encoder.Encode(merged)
+}
+
+func getConfig(key string) error {
+	layers, err := config.Layers()
+	if err != nil {
+		return err
+	}
+
+	merged, err := config.MergeLayers(layers, false)
+	if err != nil {
+		return err
+	}
+
+	if merged == nil {
+		return fmt.Errorf("key '%s' is not set", key)
+	}
+
+	value, ok := config.ValueAt(merged, key)
+	if !ok {
+		return fmt.Errorf("key '%s' is not set", key)
+	}
+
+	if value.Kind == yaml.ScalarNode {
+		fmt.Println(value.Value)
+		return nil
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	defer encoder.Close()
+	return encoder.Encode(value)
+}
+
+func setConfig(key string, value string) error {
+	if err := config.SetAtDefaultLocation(key, value); err != nil {
+		return err
+	}
+
+	printStatus("%s set to %s", key, value)
+	return nil
+}
+
+func editConfig() error {
+	location, err := config.DefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	original, err := os.ReadFile(location)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config file %s: %v", location, err)
+	}
+
+	copied, err := os.CreateTemp("", "kz-config-*.yml")
+	if err != nil {
+		return fmt.Errorf("failed to create a copy of config file %s: %v", location, err)
+	}
+	copied.Close()
+
+	if err := os.WriteFile(copied.Name(), original, 0600); err != nil {
+		os.Remove(copied.Name())
+		return fmt.Errorf("failed to create a copy of config file %s: %v", location, err)
+	}
+
+	edited, err := editFile(copied.Name())
+	if err != nil {
+		os.Remove(copied.Name())
+		return err
+	}
+
+	if bytes.Equal(original, edited) {
+		os.Remove(copied.Name())
+		printStatus("configuration not changed")
+		return nil
+	}
+
+	problems, err := validateEditedConfig(copied.Name())
+	if err != nil {
+		return fmt.Errorf("%v, changes are kept in %s", err, copied.Name())
+	}
+
+	printProblems(problems)
+	if hasErrors(problems) {
+		return fmt.Errorf("configuration is invalid, changes are kept in %s", copied.Name())
+	}
+
+	if err := config.ReplaceAtDefaultLocation(original, edited); err != nil {
+		return fmt.Errorf("%v, changes are kept in %s", err, copied.Name())
+	}
+
+	os.Remove(copied.Name())
+	printStatus("configuration saved to %s", location)
+	return nil
+}
+
+// editFile opens the file in $EDITOR, which can contain arguments, and returns its content once the editor exits
+func editFile(path string) ([]byte, error) {
+	editor := os.Getenv("EDITOR")
+	if len(editor) == 0 {
+		editor = "vi"
+	}
+
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor '%s' failed: %v", editor, err)
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited config file %s: %v", path, err)
+	}
+
+	return edited, nil
+}
+
+// validateEditedConfig validates the edited copy of user configuration file together with other layers
+func validateEditedConfig(path string) ([]config.Problem, error) {
+	layers, err := config.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, l := range layers {
+		if l.Name == config.UserLayer {
+			layers[i].Location = path
+		}
+	}
+
+	return validateLayers(layers)
+}
+
+func validateConfig() error {
+	layers, err := config.Layers()
+	if err != nil {
+		return err
+	}
+
+	problems, err := validateLayers(layers)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		printProblems(problems)
+		return fmt.Errorf("%d problem(s) found in configuration", len(problems))
+	}
+
+	printStatus("configuration is valid")
+	return nil
+}
+
+func validateLayers(layers []config.Layer) ([]config.Problem, error) {
+	contexts, err := kube.ContextsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return config.ValidateLayers(layers, contexts)
+}
+
+func printProblems(problems []config.Problem) {
+	for _, p := range problems {
+		if p.Warning {
+			printWarning("%s", p)
+			continue
+		}
+
+		color.New(color.FgRed).Fprintln(os.Stderr, p.String())
+	}
+}
+
+func hasErrors(problems []config.Problem) bool {
+	return slices.ContainsFunc(problems, func(p config.Problem) bool {
+		return !p.Warning
+	})
+}
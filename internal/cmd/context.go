@@ -5,16 +5,18 @@ import (
 	"github.com/fatih/color"
 	"github.com/hpcsc/kz/internal/config"
 	"github.com/hpcsc/kz/internal/kube"
-	"github.com/hpcsc/kz/internal/tui"
 	"github.com/urfave/cli/v2"
+	"time"
 )
 
 func newContextSubcommand() *cli.Command {
 	return &cli.Command{
-		Name:    "ctx",
-		Usage:   "commands to work with Kubernetes contexts",
-		Aliases: []string{"context"},
-		Action:  oneArgumentsAction(switchContext, "context name query is required"),
+		Name:         "ctx",
+		Usage:        "commands to work with Kubernetes contexts",
+		Aliases:      []string{"context"},
+		Flags:        []cli.Flag{newYesFlag(), newForFlag()},
+		Action:       switchAction(switchContext, pickContext),
+		BashComplete: completeContext,
 		Subcommands: []*cli.Command{
 			{
 				Name:   "sync",
@@ -22,75 +24,142 @@ func newContextSubcommand() *cli.Command {
 				Action: noArgumentsAction(syncContexts),
 			},
 			{
-				Name:   "list",
-				Usage:  "list available Kubernetes contexts",
-				Action: noArgumentsAction(listContexts),
+				Name:      "list",
+				Usage:     "list available Kubernetes contexts, optionally only the ones matching a query",
+				ArgsUsage: "[query]",
+				Flags:     []cli.Flag{newOutputFlag()},
+				Action:    outputWithOptionalArgumentAction(listContexts),
 			},
 		},
 	}
 }
 
 func syncContexts() error {
-	c, err := config.LoadFromDefaultLocation()
+	contexts, err := kube.ContextsFromConfig()
 	if err != nil {
 		return err
 	}
 
-	contexts, err := kube.ContextsFromConfig()
-	if err != nil {
+	if err := config.UpdateAtDefaultLocation(func(c *config.Config) error {
+		c.Contexts = contexts
+		return nil
+	}); err != nil {
 		return err
 	}
 
-	c.Contexts = contexts
+	printStatus("%d contexts synced", len(contexts))
+
+	return nil
+}
+
+type contextList struct {
+	Contexts []contextItem `json:"contexts" yaml:"contexts"`
+}
+
+type contextItem struct {
+	Name      string `json:"name" yaml:"name"`
+	Protected bool   `json:"protected" yaml:"protected"`
+}
 
-	if err := config.SaveToDefaultLocation(c); err != nil {
+func listContexts(output string, query string) error {
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
 		return err
 	}
 
-	color.Green(fmt.Sprintf("%d contexts synced", len(contexts)))
+	list := contextList{Contexts: []contextItem{}}
+	for _, c := range cfg.ContextsMatching(query) {
+		list.Contexts = append(list.Contexts, contextItem{
+			Name:      c,
+			Protected: cfg.IsProtected(c),
+		})
+	}
+
+	if output != textOutput {
+		return printStructured(output, list)
+	}
+
+	for _, c := range list.Contexts {
+		if c.Protected {
+			fmt.Println(c.Name, color.New(color.FgRed).Sprint("(protected)"))
+			continue
+		}
+
+		fmt.Println(c.Name)
+	}
 
 	return nil
 }
 
-func listContexts() error {
+func pickContext(opts switchOptions) error {
 	cfg, err := config.LoadFromDefaultLocation()
 	if err != nil {
 		return err
 	}
 
-	for _, c := range cfg.Contexts {
-		fmt.Println(c)
+	contexts := cfg.ContextsByRecency()
+	if len(contexts) == 0 {
+		return errNoContextsTracked
 	}
 
-	return nil
+	contextToSwitch, err := selectContext(cfg, "Please select a context", contexts)
+	if err != nil {
+		return err
+	}
+
+	return switchToContext(cfg, opts, contextToSwitch)
 }
 
-func switchContext(query string) error {
+func switchContext(opts switchOptions, query string) error {
 	cfg, err := config.LoadFromDefaultLocation()
 	if err != nil {
 		return err
 	}
 
-	destinationContexts := cfg.ContextsMatching(query)
-	if len(destinationContexts) == 0 {
-		return fmt.Errorf("no contexts matched query '%s'", query)
+	contextToSwitch, err := resolveContext(cfg, query)
+	if err != nil {
+		return err
 	}
 
-	var contextToSwitch string
-	if len(destinationContexts) == 1 {
-		contextToSwitch = destinationContexts[0]
-	} else {
-		contextToSwitch, err = tui.ShowDropdown("Please select a context", destinationContexts)
-		if err != nil {
-			return err
-		}
+	return switchToContext(cfg, opts, contextToSwitch)
+}
+
+func switchToContext(cfg *config.Config, opts switchOptions, contextToSwitch string) error {
+	if err := confirmSwitch(cfg, opts, contextToSwitch); err != nil {
+		return err
+	}
+
+	timed, err := prepareTimedSwitch(cfg, opts, contextToSwitch)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := newSwitchHooks(cfg, contextToSwitch, "")
+	if err != nil {
+		return err
+	}
+
+	if err := hooks.runPre(); err != nil {
+		return err
 	}
 
 	if err := kube.SwitchContextTo(contextToSwitch); err != nil {
 		return err
 	}
 
-	color.Green(fmt.Sprintf("switched to context %s", contextToSwitch))
+	if err := config.UpdateStateAtDefaultLocation(cfg, func(s *config.Config) {
+		s.RecordSwitch(contextToSwitch, "", time.Now())
+		recordTimedSwitch(s, timed)
+	}); err != nil {
+		return err
+	}
+
+	printStatus("switched to context %s", contextToSwitch)
+	printTimedSwitch(timed)
 
-	return nil
+	if err := applyContextEnv(cfg, contextToSwitch); err != nil {
+		return err
+	}
+
+	return hooks.runPost()
 }
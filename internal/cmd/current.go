@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func newCurrentSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:    "current",
+		Usage:   "show current context, namespace, cluster and user",
+		Aliases: []string{"status"},
+		Flags:   []cli.Flag{newOutputFlag()},
+		Action:  outputAction(showCurrent),
+	}
+}
+
+func showCurrent(output string) error {
+	status, err := kube.CurrentStatus()
+	if err != nil {
+		return err
+	}
+
+	if output != textOutput {
+		return printStructured(output, status)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Context:\t%s\t%s\n", status.Context, sourceOf(status.Sources.Context))
+	fmt.Fprintf(w, "Namespace:\t%s\t%s\n", status.Namespace, sourceOf(status.Sources.Namespace))
+	fmt.Fprintf(w, "Cluster:\t%s\t%s\n", status.Cluster, sourceOf(status.Sources.Cluster))
+	fmt.Fprintf(w, "Server:\t%s\n", status.Server)
+	fmt.Fprintf(w, "User:\t%s\t%s\n", status.AuthInfo, sourceOf(status.Sources.AuthInfo))
+	fmt.Fprintf(w, "Auth type:\t%s\n", status.AuthType)
+	fmt.Fprintf(w, "File:\t%s\n", status.File)
+
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	session, _ := kube.ActiveSession()
+	if timed, ok := cfg.TimedSwitchFor(session); ok && timed.Context == status.Context {
+		fmt.Fprintf(w, "Switch back:\tto %s/%s at %s\n", timed.PreviousContext, timed.PreviousNamespace, timed.ExpiresAt.Local().Format(time.DateTime))
+	}
+
+	return w.Flush()
+}
+
+// sourceOf explains which kubeconfig file a value of current status comes from
+func sourceOf(file string) string {
+	if len(file) == 0 {
+		return "(not set in any file)"
+	}
+
+	return fmt.Sprintf("(from %s)", file)
+}
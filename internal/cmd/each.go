@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+func newEachSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "each",
+		Usage:     "run a command against every matched context concurrently",
+		ArgsUsage: "<@tag|glob pattern|context query>... -- <command> [arguments]",
+		Description: `Run the command once per tracked context selected by the given selectors, each with its own temporary kubeconfig pinned to the context.
+Output lines are prefixed with the context name and a summary of exit codes is printed to stderr when all runs finish.
+Tags are defined in kz configuration file as lists of context glob patterns.`,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    "parallel",
+				Aliases: []string{"p"},
+				Usage:   "maximum number of commands running at the same time",
+				Value:   4,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "kill commands still running after this duration, .e.g. 30s, 0 means no timeout",
+			},
+		},
+		BashComplete: completeSelectors,
+		Action: func(ctx *cli.Context) error {
+			return runEach(ctx.Args().Slice(), ctx.Int("parallel"), ctx.Duration("timeout"))
+		},
+	}
+}
+
+type eachResult struct {
+	context  string
+	exitCode int
+	duration time.Duration
+	err      error
+}
+
+func runEach(args []string, parallel int, timeout time.Duration) error {
+	selectors, command := splitCommand(args)
+	if len(command) == 0 {
+		return errors.New("command to run is required after context selectors and --, .e.g. `kz each @prod -- kubectl get pods`")
+	}
+
+	if len(selectors) == 0 {
+		return errors.New("at least one context selector is required before --")
+	}
+
+	if parallel < 1 {
+		return fmt.Errorf("parallel must be at least 1, got %d", parallel)
+	}
+
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	contexts, err := contextsSelectedBy(cfg, selectors)
+	if err != nil {
+		return err
+	}
+
+	// commands are killed on interrupts, hangups and terminations so that their pinned kubeconfig files are removed before kz exits
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGHUP, syscall.SIGTERM)
+	defer stop()
+
+	results := make([]eachResult, len(contexts))
+	output := &sync.Mutex{}
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, contextName := range contexts {
+		wg.Add(1)
+		go func(i int, contextName string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			results[i] = runInContext(runCtx, cfg, contextName, command, timeout, output)
+		}(i, contextName)
+	}
+	wg.Wait()
+
+	return summarize(results)
+}
+
+// contextsSelectedBy returns tracked contexts selected by any of the selectors, without duplicates
+func contextsSelectedBy(cfg *config.Config, selectors []string) ([]string, error) {
+	var contexts []string
+	for _, s := range selectors {
+		selected, err := cfg.ContextsSelectedBy(s)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ctx := range selected {
+			if !slices.Contains(contexts, ctx) {
+				contexts = append(contexts, ctx)
+			}
+		}
+	}
+
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("no contexts matched %v", selectors)
+	}
+
+	return contexts, nil
+}
+
+func runInContext(parent context.Context, cfg *config.Config, contextName string, command []string, timeout time.Duration, output *sync.Mutex) eachResult {
+	result := eachResult{context: contextName, exitCode: -1}
+
+	kubeconfig, err := kube.NewPinnedConfig(contextName, "")
+	if err != nil {
+		result.err = err
+		return result
+	}
+	defer os.Remove(kubeconfig)
+
+	runCtx := parent
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, timeout)
+		defer cancel()
+	}
+
+	prefix := fmt.Sprintf("[%s] ", contextName)
+	stdout := newPrefixedWriter(os.Stdout, prefix, output)
+	stderr := newPrefixedWriter(os.Stderr, prefix, output)
+
+	c := exec.CommandContext(runCtx, command[0], command[1:]...)
+	c.Env = pinnedEnvironment(kubeconfig, cfg.EnvNames(), cfg.EnvFor(contextName))
+	c.Stdout = stdout
+	c.Stderr = stderr
+	// do not wait forever for output of processes started by a killed command
+	c.WaitDelay = time.Second
+
+	start := time.Now()
+	err = c.Run()
+	result.duration = time.Since(start)
+	stdout.Flush()
+	stderr.Flush()
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		result.err = fmt.Errorf("timed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.exitCode = exitErr.ExitCode()
+	case err != nil:
+		result.err = err
+	default:
+		result.exitCode = 0
+	}
+
+	return result
+}
+
+// summarize prints exit code of every run to stderr and returns an error when any run failed
+func summarize(results []eachResult) error {
+	failed := 0
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTEXT\tEXIT CODE\tDURATION\tERROR")
+	for _, r := range results {
+		exitCode := fmt.Sprint(r.exitCode)
+		errMsg := ""
+		if r.err != nil {
+			exitCode = "-"
+			errMsg = r.err.Error()
+		}
+
+		if r.exitCode != 0 {
+			failed++
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.context, exitCode, r.duration.Round(time.Millisecond), errMsg)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("%d of %d runs failed", failed, len(results)), 1)
+	}
+
+	return nil
+}
+
+// prefixedWriter prefixes every complete line written to it. Partial lines are buffered until the next newline or Flush
+// so that lines from concurrent commands sharing the same mutex are never interleaved
+type prefixedWriter struct {
+	w      io.Writer
+	prefix string
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func newPrefixedWriter(w io.Writer, prefix string, mu *sync.Mutex) *prefixedWriter {
+	return &prefixedWriter{
+		w:      w,
+		prefix: prefix,
+		mu:     mu,
+	}
+}
+
+func (p *prefixedWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i == -1 {
+			return len(b), nil
+		}
+
+		if err := p.writeLine(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes the remaining partial line, if any, terminated with a newline
+func (p *prefixedWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixedWriter) writeLine(line []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := fmt.Fprintf(p.w, "%s%s", p.prefix, line)
+	return err
+}
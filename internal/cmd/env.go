@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/hpcsc/kz/internal/shell"
+	"github.com/urfave/cli/v2"
+)
+
+func newEnvSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "env",
+		Usage:     "print statements that set environment variables of a context",
+		ArgsUsage: "[context query]",
+		Description: `Print export statements for environment variables configured for the context, or current context when no query is given,
+and unset statements for variables only configured for other contexts: eval "$(kz env prod)"
+
+With shell integration (see kz init), variables of the context are applied on every switch.`,
+		Flags:        []cli.Flag{newShellFlag()},
+		BashComplete: completeContext,
+		Action: func(ctx *cli.Context) error {
+			return shellAction(func(sh shell.Shell) error {
+				return printContextEnv(sh, ctx.Args().First())
+			})(ctx)
+		},
+	}
+}
+
+func printContextEnv(sh shell.Shell, query string) error {
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	var contextName string
+	if len(query) == 0 {
+		contextName, err = kube.CurrentContext()
+	} else {
+		contextName, err = resolveContext(cfg, query)
+	}
+	if err != nil {
+		return err
+	}
+
+	changes := newEnvironmentChanges(sh)
+	changes.exportContextEnv(cfg, contextName)
+	fmt.Print(changes.String())
+	return nil
+}
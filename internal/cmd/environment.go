@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/shell"
+	"github.com/urfave/cli/v2"
+	"os"
+	"strings"
+)
+
+// environment variables set by the wrapper function installed by `kz init`
+const (
+	envFileEnvVar  = "KZ_ENV_FILE"
+	envShellEnvVar = "KZ_ENV_SHELL"
+)
+
+var errNoShellIntegration = errors.New("kz is not invoked through shell integration, set it up with `kz init`")
+
+// integratedShell returns the calling shell when kz is invoked through the wrapper function installed by `kz init`
+func integratedShell() (shell.Shell, bool) {
+	if len(os.Getenv(envFileEnvVar)) == 0 {
+		return "", false
+	}
+
+	sh, err := shell.Parse(os.Getenv(envShellEnvVar))
+	if err != nil {
+		return "", false
+	}
+
+	return sh, true
+}
+
+// environmentChanges collects statements that change environment variables of the calling shell
+type environmentChanges struct {
+	shell      shell.Shell
+	statements []string
+}
+
+func newEnvironmentChanges(sh shell.Shell) *environmentChanges {
+	return &environmentChanges{shell: sh}
+}
+
+func (e *environmentChanges) export(name string, value string) {
+	e.statements = append(e.statements, shell.Export(e.shell, name, value))
+}
+
+func (e *environmentChanges) unset(name string) {
+	e.statements = append(e.statements, shell.Unset(e.shell, name))
+}
+
+// exportContextEnv exports environment variables of the context and unsets variables of other contexts
+func (e *environmentChanges) exportContextEnv(cfg *config.Config, context string) {
+	vars := cfg.EnvFor(context)
+	for _, name := range cfg.EnvNames() {
+		if value, ok := vars[name]; ok {
+			e.export(name, value)
+		} else {
+			e.unset(name)
+		}
+	}
+}
+
+func (e *environmentChanges) String() string {
+	if len(e.statements) == 0 {
+		return ""
+	}
+
+	return strings.Join(e.statements, "\n") + "\n"
+}
+
+// applyToShell appends the statements to the file that the wrapper function sources after kz exits
+func (e *environmentChanges) applyToShell() error {
+	if _, ok := integratedShell(); !ok {
+		return errNoShellIntegration
+	}
+
+	location := os.Getenv(envFileEnvVar)
+	f, err := os.OpenFile(location, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open shell integration environment file %s: %v", location, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(e.String()); err != nil {
+		return fmt.Errorf("failed to write shell integration environment file %s: %v", location, err)
+	}
+
+	return nil
+}
+
+// applyOrPrint applies the statements to the calling shell when possible,
+// otherwise prints them to stdout to be evaluated by the caller
+func (e *environmentChanges) applyOrPrint() (bool, error) {
+	if _, ok := integratedShell(); ok {
+		return true, e.applyToShell()
+	}
+
+	fmt.Print(e.String())
+	return false, nil
+}
+
+// applyContextEnv applies environment variables of the switched-to context to the calling shell when kz is invoked through shell integration
+func applyContextEnv(cfg *config.Config, context string) error {
+	sh, ok := integratedShell()
+	if !ok || len(cfg.Env) == 0 {
+		return nil
+	}
+
+	changes := newEnvironmentChanges(sh)
+	changes.exportContextEnv(cfg, context)
+	return changes.applyToShell()
+}
+
+// newShellFlag is added to commands printing statements to be evaluated by the calling shell
+func newShellFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "shell",
+		Usage: "shell to print statements for (bash, zsh, fish), detected from SHELL when not provided",
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"os"
+	"os/exec"
+)
+
+func newExecSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "exec",
+		Usage:     "run a command against a context and namespace without switching",
+		ArgsUsage: "<context query> [namespace query] -- <command> [arguments]",
+		Description: `Run the command with KUBECONFIG pointing to a temporary kubeconfig pinned to the matched context and namespace.
+The shared kubeconfig is never modified. Exit code of the command is propagated.`,
+		Action: func(ctx *cli.Context) error {
+			return execCommand(ctx.Args().Slice())
+		},
+	}
+}
+
+func execCommand(args []string) error {
+	queries, command := splitCommand(args)
+	if len(command) == 0 {
+		return errors.New("command to run is required after context query and --, .e.g. `kz exec prod -- kubectl get pods`")
+	}
+
+	if len(queries) == 0 {
+		return errors.New("context name query is required before --")
+	}
+
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	contextName, namespace, err := resolveArgs(cfg, queries)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := kube.NewPinnedConfig(contextName, namespace)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(kubeconfig)
+
+	c := exec.Command(command[0], command[1:]...)
+	c.Env = pinnedEnvironment(kubeconfig, cfg.EnvNames(), cfg.EnvFor(contextName))
+	return runAttached(c)
+}
+
+// splitCommand splits arguments into the ones before and after `--` separator.
+// Without the separator there's no command: either it's missing or cli has consumed it because no arguments came before it,
+// i.e. the query part is missing, so all arguments are reported as the ones before it
+func splitCommand(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+
+	return args, nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/urfave/cli/v2"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func newHistorySubcommand() *cli.Command {
+	return &cli.Command{
+		Name:   "history",
+		Usage:  "list context and namespace switches, most recent first",
+		Flags:  []cli.Flag{newOutputFlag()},
+		Action: outputAction(listHistory),
+	}
+}
+
+type historyList struct {
+	History []historyItem `json:"history" yaml:"history"`
+}
+
+type historyItem struct {
+	Context   string    `json:"context" yaml:"context"`
+	Namespace string    `json:"namespace" yaml:"namespace"`
+	Count     int       `json:"count" yaml:"count"`
+	LastUsed  time.Time `json:"lastUsed" yaml:"lastUsed"`
+}
+
+func listHistory(output string) error {
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	list := historyList{History: []historyItem{}}
+	for _, h := range cfg.HistoryByRecency() {
+		list.History = append(list.History, historyItem{
+			Context:   h.Context,
+			Namespace: h.Namespace,
+			Count:     h.Count,
+			LastUsed:  h.LastUsed,
+		})
+	}
+
+	if output != textOutput {
+		return printStructured(output, list)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTEXT\tNAMESPACE\tCOUNT\tLAST USED")
+	for _, h := range list.History {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", h.Context, h.Namespace, h.Count, h.LastUsed.Local().Format(time.DateTime))
+	}
+	return w.Flush()
+}
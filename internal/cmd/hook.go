@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/hpcsc/kz/internal/prompt"
+	"github.com/hpcsc/kz/internal/shell"
+	"github.com/urfave/cli/v2"
+	"strings"
+)
+
+// hookHandlers are run by hooks installed through `kz init --hook`, environment changes made by handlers are applied to the calling shell
+var hookHandlers = map[string][]func(changes *environmentChanges) error{
+	shell.PromptHook: {revertExpiredSwitchOnPrompt, exportCurrentContextAndNamespace, exportCurrentContextEnv},
+	shell.ChpwdHook:  {applyProjectContext},
+}
+
+func newHookSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "hook",
+		Usage:     "run handlers of a shell hook, called by hooks installed through kz init",
+		ArgsUsage: "<prompt|chpwd>",
+		Hidden:    true,
+		Action:    oneArgumentsAction(runHook, "hook event is required"),
+	}
+}
+
+func runHook(event string) error {
+	handlers, ok := hookHandlers[event]
+	if !ok {
+		return fmt.Errorf("unsupported hook '%s'", event)
+	}
+
+	sh, ok := integratedShell()
+	if !ok {
+		return errNoShellIntegration
+	}
+
+	changes := newEnvironmentChanges(sh)
+	for _, h := range handlers {
+		if err := h(changes); err != nil {
+			return err
+		}
+	}
+
+	return changes.applyToShell()
+}
+
+// exportCurrentContextAndNamespace exports KZ_CONTEXT and KZ_NAMESPACE to be used in shell prompt
+func exportCurrentContextAndNamespace(changes *environmentChanges) error {
+	currentContext, namespace, err := kube.CurrentContextAndNamespace()
+	if err != nil {
+		return err
+	}
+
+	changes.export("KZ_CONTEXT", currentContext)
+	changes.export("KZ_NAMESPACE", namespace)
+	return nil
+}
+
+// exportCurrentContextEnv keeps environment variables in sync with current context, .e.g. after switching in another shell.
+// Statements are cached like prompt segments since this runs on every prompt
+func exportCurrentContextEnv(changes *environmentChanges) error {
+	statements, err := prompt.Cached("env|"+string(changes.shell), func(cfg *config.Config) (string, error) {
+		if len(cfg.Env) == 0 {
+			return "", nil
+		}
+
+		currentContext, _, err := kube.CurrentContextAndNamespace()
+		if err != nil {
+			return "", err
+		}
+
+		env := newEnvironmentChanges(changes.shell)
+		env.exportContextEnv(cfg, currentContext)
+		return env.String(), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(statements) > 0 {
+		changes.statements = append(changes.statements, strings.TrimSuffix(statements, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/shell"
+	"github.com/urfave/cli/v2"
+)
+
+func newInitSubcommand() *cli.Command {
+	var subcommands []*cli.Command
+	for _, sh := range shell.Supported {
+		subcommands = append(subcommands, newInitShellSubcommand(sh))
+	}
+
+	return &cli.Command{
+		Name:  "init",
+		Usage: "print shell integration code to be evaluated by the shell",
+		Description: `Define a kz wrapper function that allows kz to change environment of current shell, and register completion.
+
+bash: add eval "$(kz init bash)" to ~/.bashrc
+zsh: add eval "$(kz init zsh)" to ~/.zshrc
+fish: add kz init fish | source to ~/.config/fish/config.fish`,
+		Subcommands: subcommands,
+	}
+}
+
+func newInitShellSubcommand(sh shell.Shell) *cli.Command {
+	return &cli.Command{
+		Name:  string(sh),
+		Usage: fmt.Sprintf("print shell integration code for %s", sh),
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "hook",
+				Usage: "install hook that runs kz on the given event (prompt, chpwd), can be repeated",
+			},
+			&cli.BoolFlag{
+				Name:  "session",
+				Usage: "start a session in every new shell so that switches only affect that shell",
+			},
+			&cli.BoolFlag{
+				Name:  "no-completion",
+				Usage: "do not register completion",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			script, err := shell.Init(sh, shell.InitOptions{
+				Hooks:      ctx.StringSlice("hook"),
+				Session:    ctx.Bool("session"),
+				Completion: !ctx.Bool("no-completion"),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(script)
+			return nil
+		},
+	}
+}
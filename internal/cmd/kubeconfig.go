@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func newKubeconfigSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "kubeconfig",
+		Usage: "commands to work with kubeconfig files modified by kz",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "backups",
+				Usage: "list backups of kubeconfig files taken before kz modified them, most recent first",
+				Description: `Backups are kept in $XDG_STATE_HOME/kz/backups (~/.local/state/kz/backups by default).
+The number of backups kept for each kubeconfig file is set by kubeconfig.backups in kz configuration, 10 by default, 0 disables backups.`,
+				Flags:  []cli.Flag{newOutputFlag()},
+				Action: outputAction(listBackups),
+			},
+			{
+				Name:        "restore",
+				Usage:       "restore kubeconfig files from a backup, the most recent one by default",
+				ArgsUsage:   "[backup id]",
+				Description: `All files backed up by the same change share the same ID and are restored together. Current content of restored files is backed up first.`,
+				Action:      optionalArgumentAction(restoreBackup, func() error { return restoreBackup("") }),
+			},
+		},
+	}
+}
+
+// configureKube applies kz configuration to how kubeconfig files are modified
+func configureKube(cfg *config.Config) error {
+	backups, err := config.BackupsLocation()
+	if err != nil {
+		return err
+	}
+
+	kube.SetBackupPolicy(kube.BackupPolicy{
+		Directory: backups,
+		Keep:      cfg.KubeconfigBackups(),
+	})
+
+	stateFile, err := cfg.KubeconfigStateFile()
+	if err != nil {
+		return err
+	}
+
+	kube.SetStateFile(stateFile)
+	return nil
+}
+
+type backupList struct {
+	Backups []backupItem `json:"backups" yaml:"backups"`
+}
+
+type backupItem struct {
+	ID        string    `json:"id" yaml:"id"`
+	File      string    `json:"file" yaml:"file"`
+	CreatedAt time.Time `json:"createdAt" yaml:"createdAt"`
+}
+
+// withDefaultBackupPolicy sets the default backup policy when configuration couldn't be loaded before the command,
+// so that backups can still be listed and restored, which is when they're needed most
+func withDefaultBackupPolicy() error {
+	if kube.HasBackupPolicy() {
+		return nil
+	}
+
+	backups, err := config.BackupsLocation()
+	if err != nil {
+		return err
+	}
+
+	kube.SetBackupPolicy(kube.BackupPolicy{
+		Directory: backups,
+		Keep:      config.DefaultKubeconfigBackups,
+	})
+	return nil
+}
+
+func listBackups(output string) error {
+	if err := withDefaultBackupPolicy(); err != nil {
+		return err
+	}
+
+	backups, err := kube.Backups()
+	if err != nil {
+		return err
+	}
+
+	list := backupList{Backups: []backupItem{}}
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		list.Backups = append(list.Backups, backupItem{
+			ID:        b.ID,
+			File:      b.File,
+			CreatedAt: b.CreatedAt,
+		})
+	}
+
+	if output != textOutput {
+		return printStructured(output, list)
+	}
+
+	if len(list.Backups) == 0 {
+		printStatus("no kubeconfig backups available")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tFILE\tCREATED AT")
+	for _, b := range list.Backups {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", b.ID, b.File, b.CreatedAt.Local().Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+func restoreBackup(id string) error {
+	if err := withDefaultBackupPolicy(); err != nil {
+		return err
+	}
+
+	restored, err := kube.RestoreBackup(id)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range restored {
+		printStatus("restored %s from backup %s", b.File, b.ID)
+	}
+	return nil
+}
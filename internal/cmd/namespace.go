@@ -1,21 +1,24 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/hpcsc/kz/internal/config"
 	"github.com/hpcsc/kz/internal/kube"
 	"github.com/hpcsc/kz/internal/tui"
 	"github.com/urfave/cli/v2"
+	"slices"
 	"strings"
+	"time"
 )
 
 func newNamespaceSubcommand() *cli.Command {
 	return &cli.Command{
-		Name:    "ns",
-		Usage:   "commands to work with Kubernetes namespaces",
-		Aliases: []string{"namespace"},
-		Action:  oneArgumentsAction(switchNamespace, "namespace name query is required"),
+		Name:         "ns",
+		Usage:        "commands to work with Kubernetes namespaces",
+		Aliases:      []string{"namespace"},
+		Action:       optionalArgumentAction(switchNamespace, pickNamespace),
+		BashComplete: completeNamespace,
 		Subcommands: []*cli.Command{
 			{
 				Name:   "add",
@@ -23,94 +26,196 @@ func newNamespaceSubcommand() *cli.Command {
 				Action: sliceArgumentsAction(addNamespaces, "no namespaces provided"),
 			},
 			{
-				Name:   "list",
-				Usage:  "list tracked Kubernetes namespaces",
-				Action: noArgumentsAction(listNamespaces),
+				Name:      "list",
+				Usage:     "list tracked Kubernetes namespaces, optionally only the ones matching a query",
+				ArgsUsage: "[query]",
+				Flags:     []cli.Flag{newOutputFlag()},
+				Action:    outputWithOptionalArgumentAction(listNamespaces),
 			},
 			{
-				Name:   "delete",
-				Usage:  "delete tracked Kubernetes namespaces",
-				Action: sliceArgumentsAction(deleteNamespaces, "no namespaces provided"),
+				Name:         "delete",
+				Usage:        "delete tracked Kubernetes namespaces",
+				Action:       sliceArgumentsAction(deleteNamespaces, "no namespaces provided"),
+				BashComplete: completeNamespacesToDelete,
 			},
 		},
 	}
 }
 
 func addNamespaces(toBeAdded []string) error {
-	c, err := config.LoadFromDefaultLocation()
-	if err != nil {
+	if err := config.UpdateAtDefaultLocation(func(c *config.Config) error {
+		c.AddNamespaces(toBeAdded...)
+		return nil
+	}); err != nil {
 		return err
 	}
 
-	c.AddNamespaces(toBeAdded...)
+	printStatus("namespace(s) %s added", strings.Join(toBeAdded, ", "))
 
-	if err := config.SaveToDefaultLocation(c); err != nil {
-		return err
-	}
+	return nil
+}
 
-	color.Green(fmt.Sprintf("namespace(s) %s added", strings.Join(toBeAdded, ", ")))
+type namespaceList struct {
+	Namespaces []namespaceItem `json:"namespaces" yaml:"namespaces"`
+}
 
-	return nil
+type namespaceItem struct {
+	Name string `json:"name" yaml:"name"`
 }
 
-func listNamespaces() error {
+func listNamespaces(output string, query string) error {
 	c, err := config.LoadFromDefaultLocation()
 	if err != nil {
 		return err
 	}
 
-	if len(c.Namespaces) == 0 {
-		fmt.Println("no namespaces available")
+	list := namespaceList{Namespaces: []namespaceItem{}}
+	for _, n := range c.NamespacesMatching(query) {
+		list.Namespaces = append(list.Namespaces, namespaceItem{Name: n})
+	}
+
+	if output != textOutput {
+		return printStructured(output, list)
+	}
+
+	if len(list.Namespaces) == 0 {
+		printStatus("no namespaces available")
 		return nil
 	}
 
-	for _, n := range c.Namespaces {
-		fmt.Println(n)
+	for _, n := range list.Namespaces {
+		fmt.Println(n.Name)
 	}
 
 	return nil
 }
 
 func deleteNamespaces(toBeDeleted []string) error {
-	c, err := config.LoadFromDefaultLocation()
+	layers, err := config.Layers()
 	if err != nil {
 		return err
 	}
 
-	c.DeleteNamespaces(toBeDeleted...)
+	// namespaces listed by team or system layer can't be deleted by changing the user layer
+	var deletable, inherited []string
+	for _, n := range toBeDeleted {
+		listing, err := config.LowerLayersListing(layers, "namespaces", n)
+		if err != nil {
+			return err
+		}
 
-	if err := config.SaveToDefaultLocation(c); err != nil {
-		return err
+		if len(listing) == 0 {
+			deletable = append(deletable, n)
+			continue
+		}
+
+		var origins []string
+		for _, l := range listing {
+			origins = append(origins, fmt.Sprintf("%s layer %s", l.Name, l.Location))
+		}
+		inherited = append(inherited, fmt.Sprintf("namespace %s comes from %s", n, strings.Join(origins, " and ")))
 	}
 
-	color.Green(fmt.Sprintf("namespace(s) %s deleted", strings.Join(toBeDeleted, ", ")))
+	if len(deletable) > 0 {
+		if err := config.UpdateAtDefaultLocation(func(c *config.Config) error {
+			c.DeleteNamespaces(deletable...)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		printStatus("namespace(s) %s deleted", strings.Join(deletable, ", "))
+	}
+
+	if len(inherited) > 0 {
+		return fmt.Errorf("%s, remove it there or replace the list in your configuration with `namespaces: !replace`", strings.Join(inherited, ", "))
+	}
 
 	return nil
 }
 
+func pickNamespace() error {
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	namespaces := cfg.NamespacesByRecency()
+	if len(namespaces) == 0 {
+		return errNoNamespacesTracked
+	}
+
+	namespaceToSwitch, err := selectOne("Please select a namespace", namespaces)
+	if err != nil {
+		return err
+	}
+
+	return switchToNamespace(cfg, namespaceToSwitch)
+}
+
 func switchNamespace(query string) error {
 	cfg, err := config.LoadFromDefaultLocation()
 	if err != nil {
 		return err
 	}
 
-	destinationNamespaces := cfg.NamespacesMatching(query)
-	var namespaceToSwitch string
-	if len(destinationNamespaces) == 0 {
-		namespaceToSwitch = query
-	} else if len(destinationNamespaces) == 1 {
-		namespaceToSwitch = destinationNamespaces[0]
-	} else {
-		namespaceToSwitch, err = tui.ShowDropdown("Please select a namespace", destinationNamespaces)
-		if err != nil {
-			return err
-		}
+	namespaceToSwitch, err := resolveNamespace(cfg, query)
+	if err != nil {
+		return err
 	}
 
+	return switchToNamespace(cfg, namespaceToSwitch)
+}
+
+func switchToNamespace(cfg *config.Config, namespaceToSwitch string) error {
 	if err := kube.SwitchNamespaceTo(namespaceToSwitch); err != nil {
+		var notFound *kube.ContextNotFoundError
+		if errors.As(err, &notFound) && notFound.Current {
+			return switchFromDanglingContext(cfg, notFound, namespaceToSwitch)
+		}
+
+		return err
+	}
+
+	currentContext, err := kube.CurrentContext()
+	if err != nil {
 		return err
 	}
 
-	color.Green(fmt.Sprintf("switched to namespace %s", namespaceToSwitch))
+	if err := config.UpdateStateAtDefaultLocation(cfg, func(s *config.Config) {
+		s.RecordSwitch(currentContext, namespaceToSwitch, time.Now())
+	}); err != nil {
+		return err
+	}
+
+	printStatus("switched to namespace %s", namespaceToSwitch)
 	return nil
 }
+
+// switchFromDanglingContext offers to pick a context that still exists when current context has been deleted from kubeconfig,
+// then switches to the namespace in the picked context. Without a terminal to pick from, the error is returned as is
+func switchFromDanglingContext(cfg *config.Config, dangling *kube.ContextNotFoundError, namespaceToSwitch string) error {
+	if !tui.IsInteractive() {
+		return dangling
+	}
+
+	existing, err := kube.ContextsFromConfig()
+	if err != nil {
+		return err
+	}
+
+	contexts := slices.DeleteFunc(cfg.ContextsByRecency(), func(c string) bool {
+		return !slices.Contains(existing, c)
+	})
+	if len(contexts) == 0 {
+		return dangling
+	}
+
+	printWarning("current context %s does not exist in kube config file(s)", dangling.Name)
+	contextToSwitch, err := selectContext(cfg, fmt.Sprintf("Please select a context to switch to namespace %s in", namespaceToSwitch), contexts)
+	if err != nil {
+		return err
+	}
+
+	return switchToContextAndNamespace(cfg, switchOptions{}, contextToSwitch, namespaceToSwitch)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	textOutput = "text"
+	jsonOutput = "json"
+	yamlOutput = "yaml"
+)
+
+var outputFormats = []string{textOutput, jsonOutput, yamlOutput}
+
+// newOutputFlag is shared by root command and every command with output, see flagContext
+func newOutputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage:   fmt.Sprintf("output format (%s)", strings.Join(outputFormats, ", ")),
+		Value:   textOutput,
+	}
+}
+
+// outputFrom returns output format set on the command or any of its parents, .e.g. `kz -o json ctx list` or `kz ctx list -o json`
+func outputFrom(ctx *cli.Context) string {
+	if c := flagContext(ctx, "output"); c != nil {
+		return c.String("output")
+	}
+
+	return textOutput
+}
+
+// flagContext returns the closest context in the lineage that has the flag set, or nil when the flag is not set.
+// Shared flags like `--output`, `--yes` and `--for` are added to root command and to each command using them,
+// so that they're accepted before the command name, after it and, for commands parsing their arguments with argumentsOf, after the arguments
+func flagContext(ctx *cli.Context, name string) *cli.Context {
+	for _, c := range ctx.Lineage() {
+		if c.IsSet(name) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func validateOutput(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("unsupported output format '%s', supported formats: %s", format, strings.Join(outputFormats, ", "))
+	}
+
+	return nil
+}
+
+// printStatus prints human-readable status messages to stderr so that stdout only contains command output
+func printStatus(format string, a ...any) {
+	color.New(color.FgGreen).Fprintf(os.Stderr, format+"\n", a...)
+}
+
+func printWarning(format string, a ...any) {
+	color.New(color.FgYellow).Fprintf(os.Stderr, format+"\n", a...)
+}
+
+func printError(err error) {
+	color.New(color.FgRed).Fprintln(os.Stderr, err.Error())
+}
+
+// printStructured prints v in the given machine-readable format
+func printStructured(format string, v any) error {
+	switch format {
+	case jsonOutput:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case yamlOutput:
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(v)
+	default:
+		return validateOutput(format)
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"k8s.io/client-go/tools/clientcmd"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// pinnedEnvironment returns environment of current process with KUBECONFIG replaced by the given pinned kubeconfig,
+// and variables of other contexts, i.e. the ones in names, replaced by variables of the pinned context.
+// Variables of shell integration are dropped so that kz invoked by the command doesn't change environment of the calling shell
+func pinnedEnvironment(kubeconfig string, names []string, vars map[string]string, extra ...string) []string {
+	dropped := append([]string{clientcmd.RecommendedConfigPathEnvVar, kube.SessionEnvVar, envFileEnvVar, envShellEnvVar}, names...)
+	var env []string
+	for _, e := range os.Environ() {
+		if slices.ContainsFunc(dropped, func(name string) bool { return hasEnvName(e, name) }) {
+			continue
+		}
+		env = append(env, e)
+	}
+
+	env = append(env, fmt.Sprintf("%s=%s", clientcmd.RecommendedConfigPathEnvVar, kubeconfig))
+	for _, name := range names {
+		if value, ok := vars[name]; ok {
+			env = append(env, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	return append(env, extra...)
+}
+
+func hasEnvName(entry string, name string) bool {
+	return strings.HasPrefix(entry, name+"=")
+}
+
+// runAttached runs the command attached to standard streams of current process and propagates its exit code.
+// Interrupts are left to the child to handle. Hangups and terminations are forwarded to the child instead of terminating kz,
+// so that callers can clean up after the child exits, .e.g. remove the pinned kubeconfig
+func runAttached(c *exec.Cmd) error {
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case s := <-signals:
+				if s != os.Interrupt {
+					_ = c.Process.Signal(s)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := c.Wait()
+	close(done)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// shells report children killed by a signal with 128 + signal number
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return cli.Exit("", 128+int(status.Signal()))
+		}
+
+		return cli.Exit("", exitErr.ExitCode())
+	}
+
+	return err
+}
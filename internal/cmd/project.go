@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"k8s.io/client-go/tools/clientcmd"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// applyProjectContext is run by chpwd hook. It switches to the context and namespace declared by project configuration of the working directory,
+// and warns when current context is not allowed in the project
+func applyProjectContext(changes *environmentChanges) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	userConfig, err := config.DefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	legacyConfig, err := config.LegacyLocation()
+	if err != nil {
+		return err
+	}
+
+	project, found, err := config.FindProject(dir, userConfig, legacyConfig)
+	if err != nil || !found {
+		return err
+	}
+
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	if len(project.Context) > 0 {
+		if err := switchToProjectContext(cfg, project, changes); err != nil {
+			return err
+		}
+	}
+
+	currentContext, _, err := kube.CurrentContextAndNamespace()
+	if err != nil {
+		return err
+	}
+
+	if !project.IsAllowed(currentContext) {
+		printWarning("context %s is not allowed in project %s, allowed contexts: %s", currentContext, projectDirectory(project), strings.Join(project.AllowedContexts, ", "))
+	}
+
+	return nil
+}
+
+// switchToProjectContext switches in a session, which is started when needed, so that entering a directory never changes the shared kubeconfig.
+// Protected contexts are never switched to automatically
+func switchToProjectContext(cfg *config.Config, project *config.Project, changes *environmentChanges) error {
+	currentContext, currentNamespace, err := kube.CurrentContextAndNamespace()
+	if err != nil {
+		return err
+	}
+
+	if currentContext == project.Context && (len(project.Namespace) == 0 || currentNamespace == project.Namespace) {
+		return nil
+	}
+
+	if cfg.IsProtected(project.Context) {
+		printWarning("project %s uses protected context %s, switch to it with `kz ctx %s`", projectDirectory(project), project.Context, project.Context)
+		return nil
+	}
+
+	if _, ok := kube.ActiveSession(); !ok {
+		session, err := kube.StartSession()
+		if err != nil {
+			return err
+		}
+
+		changes.export(clientcmd.RecommendedConfigPathEnvVar, session.Kubeconfig)
+		changes.export(kube.SessionEnvVar, session.Overlay)
+		// the switch below is made by this process and has to go to the new session
+		os.Setenv(clientcmd.RecommendedConfigPathEnvVar, session.Kubeconfig)
+		os.Setenv(kube.SessionEnvVar, session.Overlay)
+		printStatus("session started for project %s", projectDirectory(project))
+	}
+
+	if len(project.Namespace) == 0 {
+		return switchToContext(cfg, switchOptions{}, project.Context)
+	}
+
+	return switchToContextAndNamespace(cfg, switchOptions{}, project.Context, project.Namespace)
+}
+
+func projectDirectory(project *config.Project) string {
+	return filepath.Dir(project.Location)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/prompt"
+	"github.com/urfave/cli/v2"
+)
+
+func newPromptSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "prompt",
+		Usage: "print current context and namespace for shell prompts, starship or tmux",
+		Description: `Available fields: .Context (display name from prompt settings), .FullContext, .Namespace, .Color, .Protected
+Available functions: color <name> <text>
+
+Output is cached until kubeconfig files or kz configuration file change.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Go template of the output",
+				Value: prompt.DefaultFormat,
+			},
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: "do not color output, .e.g. for tmux status line",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			output, err := prompt.Render(prompt.Options{
+				Format:  ctx.String("format"),
+				NoColor: ctx.Bool("no-color"),
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(output)
+			return nil
+		},
+	}
+}
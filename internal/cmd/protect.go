@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"os"
+	"strings"
+)
+
+// newYesFlag is shared by root command and commands switching context, see flagContext
+func newYesFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:    "yes",
+		Aliases: []string{"y"},
+		Usage:   "switch to protected contexts without confirmation",
+	}
+}
+
+// confirmSwitch shows a warning banner when switching to a protected context and asks for the context name to be typed.
+// No confirmation is needed with `--yes` or when the context is already the current one
+func confirmSwitch(cfg *config.Config, opts switchOptions, contextToSwitch string) error {
+	if !cfg.IsProtected(contextToSwitch) {
+		return nil
+	}
+
+	currentContext, err := kube.CurrentContext()
+	if err != nil {
+		return err
+	}
+
+	if currentContext == contextToSwitch {
+		return nil
+	}
+
+	banner := color.New(color.FgWhite, color.BgRed, color.Bold)
+	banner.Fprintf(os.Stderr, " context %s is protected ", contextToSwitch)
+	fmt.Fprintln(os.Stderr)
+
+	if opts.yes {
+		return nil
+	}
+
+	names := []string{contextToSwitch}
+	if p, ok := cfg.PromptContextFor(contextToSwitch); ok && len(p.Name) > 0 {
+		names = append(names, p.Name)
+	}
+
+	fmt.Fprintf(os.Stderr, "type %s to switch: ", strings.Join(names, " or "))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if err != nil && len(answer) == 0 {
+		return fmt.Errorf("switch to protected context %s requires confirmation, use --yes to switch without it", contextToSwitch)
+	}
+
+	for _, n := range names {
+		if answer == n {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("confirmation did not match, not switching to protected context %s", contextToSwitch)
+}
+
+// protectedLabel marks protected contexts in dropdowns
+func protectedLabel(cfg *config.Config, contextName string, label string) string {
+	if cfg.IsProtected(contextName) {
+		return label + " (protected)"
+	}
+
+	return label
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"strings"
+)
+
+// queriesFromArgs converts root command arguments into a context query and a namespace query.
+// An empty context query means current context, an empty namespace query means the namespace is not changed
+func queriesFromArgs(args []string) (string, string, error) {
+	if len(args) > 2 {
+		return "", "", fmt.Errorf("unexpected argument(s) %s, only a context query and a namespace query are accepted", strings.Join(args[2:], " "))
+	}
+
+	if len(args) > 1 {
+		contextQuery := args[0]
+		if contextQuery == "-" {
+			contextQuery = ""
+		}
+
+		return contextQuery, args[1], nil
+	}
+
+	query := args[0]
+	separatorIndex := strings.LastIndexAny(query, "/:")
+	if separatorIndex == -1 {
+		return query, "", nil
+	}
+
+	// context names like EKS ARNs contain separators themselves, treat the whole query as context query when it matches a tracked context
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(cfg.ContextsMatching(query)) > 0 {
+		return query, "", nil
+	}
+
+	contextQuery, namespaceQuery := query[:separatorIndex], query[separatorIndex+1:]
+	if len(contextQuery) == 0 && len(namespaceQuery) == 0 {
+		return "", "", fmt.Errorf("either context or namespace query is required in '%s'", query)
+	}
+
+	return contextQuery, namespaceQuery, nil
+}
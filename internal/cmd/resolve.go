@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/hpcsc/kz/internal/tui"
+)
+
+var (
+	errNoContextsTracked   = errors.New("no contexts tracked, run `kz ctx sync` to track contexts from kube config")
+	errNoNamespacesTracked = errors.New("no namespaces tracked, run `kz ns add` to track namespaces")
+)
+
+func resolveContext(cfg *config.Config, query string) (string, error) {
+	destinationContexts := cfg.ContextsMatching(query)
+	if len(destinationContexts) == 0 {
+		return "", fmt.Errorf("no contexts matched query '%s'", query)
+	}
+
+	return selectContext(cfg, "Please select a context", destinationContexts)
+}
+
+// resolveNamespace returns the query itself when it doesn't match any tracked namespace
+// so that untracked namespaces can still be switched to
+func resolveNamespace(cfg *config.Config, query string) (string, error) {
+	destinationNamespaces := cfg.NamespacesMatching(query)
+	if len(destinationNamespaces) == 0 {
+		return query, nil
+	}
+
+	return selectOne("Please select a namespace", destinationNamespaces)
+}
+
+// selectOne only shows the dropdown when there's more than one option to select from
+func selectOne(label string, options []string) (string, error) {
+	if len(options) == 1 {
+		return options[0], nil
+	}
+
+	return tui.ShowDropdown(label, options)
+}
+
+// selectContext is selectOne with protected contexts marked in the dropdown
+func selectContext(cfg *config.Config, label string, contexts []string) (string, error) {
+	if len(contexts) == 1 {
+		return contexts[0], nil
+	}
+
+	options := make([]string, len(contexts))
+	contextsByOption := make(map[string]string, len(contexts))
+	for i, c := range contexts {
+		options[i] = protectedLabel(cfg, c, c)
+		contextsByOption[options[i]] = c
+	}
+
+	selected, err := tui.ShowDropdown(label, options)
+	if err != nil {
+		return "", err
+	}
+
+	return contextsByOption[selected], nil
+}
+
+// resolveArgs resolves context and namespace from arguments, using the same query syntax as root command, without switching
+func resolveArgs(cfg *config.Config, args []string) (string, string, error) {
+	contextQuery, namespaceQuery, err := queriesFromArgs(args)
+	if err != nil {
+		return "", "", err
+	}
+
+	return resolveContextAndNamespace(cfg, contextQuery, namespaceQuery)
+}
+
+// resolveContextAndNamespace resolves queries returned by queriesFromArgs without switching.
+// An empty context query resolves to current context, an empty namespace query resolves to an empty namespace
+func resolveContextAndNamespace(cfg *config.Config, contextQuery string, namespaceQuery string) (string, string, error) {
+	var contextName string
+	var err error
+	if len(contextQuery) == 0 {
+		contextName, err = kube.CurrentContext()
+		if err != nil {
+			return "", "", err
+		}
+
+		if len(contextName) == 0 {
+			return "", "", errors.New("context query is required because current context is not set")
+		}
+	} else {
+		contextName, err = resolveContext(cfg, contextQuery)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if len(namespaceQuery) == 0 {
+		return contextName, "", nil
+	}
+
+	namespace, err := resolveNamespace(cfg, namespaceQuery)
+	if err != nil {
+		return "", "", err
+	}
+
+	return contextName, namespace, nil
+}
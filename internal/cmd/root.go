@@ -1,13 +1,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"github.com/fatih/color"
 	"github.com/hpcsc/kz/internal/config"
 	"github.com/hpcsc/kz/internal/kube"
-	"github.com/hpcsc/kz/internal/tui"
 	"github.com/urfave/cli/v2"
 	"os"
+	"slices"
+	"time"
 )
 
 var Version = "main"
@@ -18,81 +19,211 @@ func Run() int {
 		Usage:                "switch Kubernetes namespace and context using partial name",
 		Version:              Version,
 		EnableBashCompletion: true,
-		Action:               switchFromRoot,
+		// exit codes are returned from Run below instead of exiting the process directly
+		ExitErrHandler: func(_ *cli.Context, _ error) {},
+		Action:         switchFromRoot,
+		BashComplete:   completeContextAndNamespace,
+		Before:         beforeCommand,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "location of kz configuration file, ~/.config/kz/config.yml by default",
+				EnvVars: []string{config.LocationEnvVar},
+			},
+			newOutputFlag(),
+			newYesFlag(),
+			newForFlag(),
+		},
 		Commands: []*cli.Command{
 			newNamespaceSubcommand(),
 			newContextSubcommand(),
+			newSessionSubcommand(),
+			newShellSubcommand(),
+			newExecSubcommand(),
+			newEachSubcommand(),
+			newEnvSubcommand(),
+			newInitSubcommand(),
+			newCompletionSubcommand(),
+			newPromptSubcommand(),
+			newCurrentSubcommand(),
+			newHistorySubcommand(),
+			newConfigSubcommand(),
+			newKubeconfigSubcommand(),
+			newHookSubcommand(),
 			newUpdateSubcommand(),
 		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		color.Red(err.Error())
+		if len(err.Error()) > 0 {
+			printError(err)
+		}
+
+		var exitCoder cli.ExitCoder
+		if errors.As(err, &exitCoder) {
+			return exitCoder.ExitCode()
+		}
+
 		return 1
 	}
 
 	return 0
 }
 
+func beforeCommand(ctx *cli.Context) error {
+	// configuration location is read from environment so that it's also used by kz invoked from hooks and commands run by kz
+	if ctx.IsSet("config") {
+		if err := os.Setenv(config.LocationEnvVar, ctx.String("config")); err != nil {
+			return err
+		}
+	}
+
+	if isFrequentInvocation(ctx) {
+		return nil
+	}
+
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		// commands still work, .e.g. to fix the configuration, and those needing configuration report the error themselves
+		return nil
+	}
+
+	if err := configureKube(cfg); err != nil {
+		return err
+	}
+
+	// commands still work, .e.g. to fix the kubeconfig, when an expired switch can't be reverted
+	if err := revertExpiredSwitch(cfg, time.Now()); err != nil {
+		printError(fmt.Errorf("failed to revert expired switch: %v", err))
+	}
+
+	return nil
+}
+
+// isFrequentInvocation reports whether kz is invoked on every prompt or key press, i.e. prompt, hooks and shell completion.
+// Those skip loading configuration and reverting expired switches to stay cheap, and so that kubeconfig is never modified in the middle of completing a command.
+// The prompt hook reverts expired switches itself, see revertExpiredSwitchOnPrompt
+func isFrequentInvocation(ctx *cli.Context) bool {
+	if slices.Contains(os.Args, "--"+cli.BashCompletionFlag.Names()[0]) {
+		return true
+	}
+
+	return slices.Contains([]string{"prompt", "hook", "completion"}, ctx.Args().First())
+}
+
 func switchFromRoot(ctx *cli.Context) error {
-	if ctx.Args().Len() == 1 {
-		query := ctx.Args().First()
-		return switchContext(query)
+	args, err := argumentsOf(ctx, 2)
+	if err != nil {
+		return err
 	}
 
-	if ctx.Args().Len() > 1 {
-		contextQuery := ctx.Args().Get(0)
-		namespaceQuery := ctx.Args().Get(1)
+	opts := switchOptionsFrom(ctx)
+	if len(args) == 0 {
+		return pickContextAndNamespace(opts)
+	}
 
-		if contextQuery == "-" {
-			return switchNamespace(namespaceQuery)
-		}
+	contextQuery, namespaceQuery, err := queriesFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if len(contextQuery) == 0 {
+		return switchNamespace(namespaceQuery)
+	}
 
-		return switchContextAndNamespace(contextQuery, namespaceQuery)
+	if len(namespaceQuery) == 0 {
+		return switchContext(opts, contextQuery)
 	}
 
-	return fmt.Errorf("context name query is required")
+	return switchContextAndNamespace(opts, contextQuery, namespaceQuery)
 }
 
-func switchContextAndNamespace(contextQuery string, namespaceQuery string) error {
+func pickContextAndNamespace(opts switchOptions) error {
 	cfg, err := config.LoadFromDefaultLocation()
 	if err != nil {
 		return err
 	}
 
-	destinationContexts := cfg.ContextsMatching(contextQuery)
-	if len(destinationContexts) == 0 {
-		return fmt.Errorf("no contexts matched query '%s'", contextQuery)
+	if len(cfg.Contexts) == 0 {
+		return errNoContextsTracked
 	}
 
-	var contextToSwitch string
-	if len(destinationContexts) == 1 {
-		contextToSwitch = destinationContexts[0]
-	} else {
-		contextToSwitch, err = tui.ShowDropdown("Please select a context", destinationContexts)
-		if err != nil {
-			return err
-		}
+	if len(cfg.Namespaces) == 0 {
+		return pickContext(opts)
 	}
 
-	destinationNamespaces := cfg.NamespacesMatching(namespaceQuery)
-	var namespaceToSwitch string
-	if len(destinationNamespaces) == 0 {
-		namespaceToSwitch = namespaceQuery
-	} else if len(destinationNamespaces) == 1 {
-		namespaceToSwitch = destinationNamespaces[0]
-	} else {
-		namespaceToSwitch, err = tui.ShowDropdown("Please select a namespace", destinationNamespaces)
-		if err != nil {
-			return err
-		}
+	combinations := cfg.CombinationsByRecency()
+	options := make([]string, len(combinations))
+	combinationsByOption := make(map[string]config.Combination, len(combinations))
+	for i, c := range combinations {
+		options[i] = protectedLabel(cfg, c.Context, fmt.Sprintf("%s/%s", c.Context, c.Namespace))
+		combinationsByOption[options[i]] = c
+	}
+
+	selected, err := selectOne("Please select a context and namespace", options)
+	if err != nil {
+		return err
+	}
+
+	combination := combinationsByOption[selected]
+	return switchToContextAndNamespace(cfg, opts, combination.Context, combination.Namespace)
+}
+
+func switchContextAndNamespace(opts switchOptions, contextQuery string, namespaceQuery string) error {
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	contextToSwitch, err := resolveContext(cfg, contextQuery)
+	if err != nil {
+		return err
+	}
+
+	namespaceToSwitch, err := resolveNamespace(cfg, namespaceQuery)
+	if err != nil {
+		return err
+	}
+
+	return switchToContextAndNamespace(cfg, opts, contextToSwitch, namespaceToSwitch)
+}
+
+func switchToContextAndNamespace(cfg *config.Config, opts switchOptions, contextToSwitch string, namespaceToSwitch string) error {
+	if err := confirmSwitch(cfg, opts, contextToSwitch); err != nil {
+		return err
+	}
+
+	timed, err := prepareTimedSwitch(cfg, opts, contextToSwitch)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := newSwitchHooks(cfg, contextToSwitch, namespaceToSwitch)
+	if err != nil {
+		return err
+	}
+
+	if err := hooks.runPre(); err != nil {
+		return err
 	}
 
 	if err := kube.SwitchContextAndNamespace(contextToSwitch, namespaceToSwitch); err != nil {
 		return err
 	}
 
-	color.Green(fmt.Sprintf("switched to context %s, namespace %s", contextToSwitch, namespaceToSwitch))
+	if err := config.UpdateStateAtDefaultLocation(cfg, func(s *config.Config) {
+		s.RecordSwitch(contextToSwitch, namespaceToSwitch, time.Now())
+		recordTimedSwitch(s, timed)
+	}); err != nil {
+		return err
+	}
+
+	printStatus("switched to context %s, namespace %s", contextToSwitch, namespaceToSwitch)
+	printTimedSwitch(timed)
 
-	return nil
+	if err := applyContextEnv(cfg, contextToSwitch); err != nil {
+		return err
+	}
+
+	return hooks.runPost()
 }
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/hpcsc/kz/internal/shell"
+	"github.com/urfave/cli/v2"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func newSessionSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:  "session",
+		Usage: "isolate context and namespace switches to current shell",
+		Description: `Session mode keeps a per-shell kubeconfig overlay in front of the real kubeconfig files in KUBECONFIG.
+Switches made while a session is active only update the overlay, other shells and tools keep using the real kubeconfig files.
+
+With shell integration (see kz init), run kz session start directly. Otherwise: eval "$(kz session start)"`,
+		Subcommands: []*cli.Command{
+			{
+				Name:   "start",
+				Usage:  "start a session in current shell",
+				Flags:  []cli.Flag{newShellFlag()},
+				Action: shellAction(startSession),
+			},
+			{
+				Name:   "end",
+				Usage:  "end the session in current shell",
+				Flags:  []cli.Flag{newShellFlag()},
+				Action: shellAction(endSession),
+			},
+		},
+	}
+}
+
+func startSession(sh shell.Shell) error {
+	session, err := kube.StartSession()
+	if err != nil {
+		return err
+	}
+
+	changes := newEnvironmentChanges(sh)
+	changes.export(clientcmd.RecommendedConfigPathEnvVar, session.Kubeconfig)
+	changes.export(kube.SessionEnvVar, session.Overlay)
+	applied, err := changes.applyOrPrint()
+	if err != nil {
+		return err
+	}
+
+	if applied {
+		printStatus("session started, switches only affect current shell")
+	}
+
+	return nil
+}
+
+func endSession(sh shell.Shell) error {
+	kubeconfig, err := kube.EndSession()
+	if err != nil {
+		return err
+	}
+
+	changes := newEnvironmentChanges(sh)
+	changes.export(clientcmd.RecommendedConfigPathEnvVar, kubeconfig)
+	changes.unset(kube.SessionEnvVar)
+	applied, err := changes.applyOrPrint()
+	if err != nil {
+		return err
+	}
+
+	if applied {
+		printStatus("session ended")
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"os"
+	"os/exec"
+)
+
+const shellContextEnvVar = "KZ_SHELL_CONTEXT"
+
+func newShellSubcommand() *cli.Command {
+	return &cli.Command{
+		Name:      "shell",
+		Usage:     "start a subshell with an isolated context and namespace",
+		ArgsUsage: "<context query> [namespace query]",
+		Description: `Start $SHELL with KUBECONFIG pointing to a temporary kubeconfig pinned to the matched context and namespace.
+The shared kubeconfig is never modified. KZ_SHELL_CONTEXT is set to the pinned context so that it can be used in prompt.`,
+		Action: sliceArgumentsAction(startShell, "context name query is required"),
+	}
+}
+
+func startShell(args []string) error {
+	if current := os.Getenv(shellContextEnvVar); len(current) > 0 {
+		return fmt.Errorf("already in a kz shell for context %s, exit it before starting another one", current)
+	}
+
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	contextName, namespace, err := resolveArgs(cfg, args)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := kube.NewPinnedConfig(contextName, namespace)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(kubeconfig)
+
+	shellPath := os.Getenv("SHELL")
+	if len(shellPath) == 0 {
+		shellPath = "/bin/sh"
+	}
+
+	c := exec.Command(shellPath)
+	c.Env = pinnedEnvironment(kubeconfig, cfg.EnvNames(), cfg.EnvFor(contextName), fmt.Sprintf("%s=%s", shellContextEnvVar, contextName))
+	return runAttached(c)
+}
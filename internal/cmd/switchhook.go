@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"os"
+	"os/exec"
+)
+
+// switchHooks runs pre and post switch hooks configured for the context being switched to
+type switchHooks struct {
+	hooks []config.SwitchHook
+	env   []string
+}
+
+// newSwitchHooks only reads kubeconfig when there are hooks to run, namespace is the namespace of the context when it's not switched
+func newSwitchHooks(cfg *config.Config, contextToSwitch string, namespaceToSwitch string) (*switchHooks, error) {
+	hooks := cfg.SwitchHooksFor(contextToSwitch)
+	if len(hooks) == 0 {
+		return &switchHooks{}, nil
+	}
+
+	previousContext, err := kube.CurrentContext()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(namespaceToSwitch) == 0 {
+		namespaceToSwitch, err = kube.NamespaceOf(contextToSwitch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &switchHooks{
+		hooks: hooks,
+		env: append(os.Environ(),
+			"KZ_CONTEXT="+contextToSwitch,
+			"KZ_NAMESPACE="+namespaceToSwitch,
+			"KZ_PREVIOUS_CONTEXT="+previousContext,
+		),
+	}, nil
+}
+
+// runPre runs pre-switch hooks in order and stops at the first failure so that the switch can be aborted
+func (h *switchHooks) runPre() error {
+	for _, hook := range h.hooks {
+		if len(hook.Pre) == 0 {
+			continue
+		}
+
+		if err := h.run(hook.Pre); err != nil {
+			return fmt.Errorf("pre-switch hook '%s' failed, switch aborted: %v", hook.Pre, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *switchHooks) runPost() error {
+	for _, hook := range h.hooks {
+		if len(hook.Post) == 0 {
+			continue
+		}
+
+		if err := h.run(hook.Post); err != nil {
+			return fmt.Errorf("post-switch hook '%s' failed: %v", hook.Post, err)
+		}
+	}
+
+	return nil
+}
+
+// run runs the hook with `sh -c`. Hook output goes to stderr so that stdout only contains kz output
+func (h *switchHooks) run(command string) error {
+	c := exec.Command("sh", "-c", command)
+	c.Env = h.env
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	return c.Run()
+}
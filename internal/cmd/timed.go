@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"github.com/urfave/cli/v2"
+	"time"
+)
+
+// newForFlag is shared by root command and commands switching context, see flagContext
+func newForFlag() cli.Flag {
+	return &cli.DurationFlag{
+		Name:  "for",
+		Usage: "switch back to current context and namespace after this duration, .e.g. 15m",
+	}
+}
+
+type switchOptions struct {
+	yes      bool
+	duration time.Duration
+}
+
+// switchOptionsFrom returns switch options set on the command or any of its parents
+func switchOptionsFrom(ctx *cli.Context) switchOptions {
+	var opts switchOptions
+	if c := flagContext(ctx, "yes"); c != nil {
+		opts.yes = c.Bool("yes")
+	}
+	if c := flagContext(ctx, "for"); c != nil {
+		opts.duration = c.Duration("for")
+	}
+	return opts
+}
+
+// prepareTimedSwitch returns the time-limited switch to record when switching to the given context, or nil when the switch is not time-limited.
+// Switches to protected contexts are limited to the configured maximum duration even without `--for`
+func prepareTimedSwitch(cfg *config.Config, opts switchOptions, contextToSwitch string) (*config.TimedSwitch, error) {
+	if opts.duration < 0 {
+		return nil, fmt.Errorf("switch duration must be positive, got %s", opts.duration)
+	}
+
+	duration := opts.duration
+	if max := cfg.ProtectedMaxDuration; max > 0 && cfg.IsProtected(contextToSwitch) && (duration == 0 || duration > max) {
+		duration = max
+	}
+
+	if duration == 0 {
+		return nil, nil
+	}
+
+	previousContext, previousNamespace, err := kube.CurrentContextAndNamespace()
+	if err != nil {
+		return nil, err
+	}
+
+	// switching again to the same context extends the switch instead of reverting to the context itself
+	session, _ := kube.ActiveSession()
+	if existing, ok := cfg.TimedSwitchFor(session); ok && existing.Context == contextToSwitch {
+		previousContext, previousNamespace = existing.PreviousContext, existing.PreviousNamespace
+	}
+
+	if len(previousContext) == 0 {
+		return nil, errors.New("switch cannot be time-limited because there's no current context to switch back to")
+	}
+
+	if previousContext == contextToSwitch {
+		return nil, nil
+	}
+
+	return &config.TimedSwitch{
+		Context:           contextToSwitch,
+		PreviousContext:   previousContext,
+		PreviousNamespace: previousNamespace,
+		ExpiresAt:         time.Now().Add(duration),
+	}, nil
+}
+
+// recordTimedSwitch replaces time-limited switch of current session so that a switch without `--for` cancels the previous one
+func recordTimedSwitch(cfg *config.Config, timed *config.TimedSwitch) {
+	session, _ := kube.ActiveSession()
+	cfg.SetTimedSwitch(session, timed)
+}
+
+func printTimedSwitch(timed *config.TimedSwitch) {
+	if timed == nil {
+		return
+	}
+
+	printStatus("switching back to context %s, namespace %s at %s", timed.PreviousContext, timed.PreviousNamespace, timed.ExpiresAt.Format(time.TimeOnly))
+}
+
+// revertExpiredSwitchOnPrompt is run by the prompt hook, which skips loading configuration before commands to stay cheap.
+// Only state is read on every prompt, configuration is loaded once the time-limited switch of current session has expired
+func revertExpiredSwitchOnPrompt(_ *environmentChanges) error {
+	now := time.Now()
+	s, err := config.LoadStateFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	session, _ := kube.ActiveSession()
+	if timed, ok := s.TimedSwitchFor(session); !ok || now.Before(timed.ExpiresAt) {
+		return nil
+	}
+
+	cfg, err := config.LoadFromDefaultLocation()
+	if err != nil {
+		return err
+	}
+
+	if err := configureKube(cfg); err != nil {
+		return err
+	}
+
+	return revertExpiredSwitch(cfg, now)
+}
+
+// revertExpiredSwitch runs before every command and switches back from an expired time-limited switch of current session.
+// The switch is only forgotten when current context has been changed since
+func revertExpiredSwitch(cfg *config.Config, now time.Time) error {
+	session, _ := kube.ActiveSession()
+	timed, ok := cfg.TimedSwitchFor(session)
+	if !ok || now.Before(timed.ExpiresAt) {
+		return nil
+	}
+
+	currentContext, err := kube.CurrentContext()
+	if err != nil {
+		return err
+	}
+
+	if currentContext == timed.Context {
+		if err := kube.SwitchContextAndNamespace(timed.PreviousContext, timed.PreviousNamespace); err != nil {
+			return err
+		}
+
+		printStatus("switch to context %s expired, switched back to context %s, namespace %s", timed.Context, timed.PreviousContext, timed.PreviousNamespace)
+		if err := applyContextEnv(cfg, timed.PreviousContext); err != nil {
+			return err
+		}
+	}
+
+	return config.UpdateStateAtDefaultLocation(cfg, func(s *config.Config) {
+		s.SetTimedSwitch(session, nil)
+	})
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"github.com/fatih/color"
 	"github.com/hpcsc/kz/internal/gateway"
 	"github.com/hpcsc/kz/internal/updater"
 	"github.com/urfave/cli/v2"
@@ -32,7 +31,7 @@ func update() error {
 	}
 
 	if msg != "" {
-		color.Green(msg)
+		printStatus("%s", msg)
 	}
 
 	return nil
@@ -1,18 +1,107 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
-	"path"
+	"path/filepath"
+	"regexp"
 	"slices"
+	"sort"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	Contexts   []string
-	Namespaces []string
+	// Version is the schema version of the file, see currentVersion
+	Version    int                 `yaml:"version"`
+	Contexts   []string            `yaml:"contexts"`
+	Namespaces []string            `yaml:"namespaces"`
+	Prompt     Prompt              `yaml:"prompt,omitempty"`
+	Tags       map[string][]string `yaml:"tags,omitempty"`
+	// Protected contains glob patterns of contexts that require confirmation to switch to
+	Protected []string `yaml:"protected,omitempty"`
+	// ProtectedMaxDuration limits how long switches to protected contexts last, zero means no limit
+	ProtectedMaxDuration time.Duration `yaml:"protectedMaxDuration,omitempty"`
+	Hooks                []SwitchHook  `yaml:"hooks,omitempty"`
+	Env                  []ContextEnv  `yaml:"env,omitempty"`
+	Kubeconfig           Kubeconfig    `yaml:"kubeconfig,omitempty"`
+
+	// History and TimedSwitches are state, stored separately from configuration
+	History       []HistoryEntry `yaml:"-"`
+	TimedSwitches []TimedSwitch  `yaml:"-"`
+}
+
+// state is the part of Config that changes with every switch
+type state struct {
+	Version       int            `yaml:"version"`
+	History       []HistoryEntry `yaml:"history"`
+	TimedSwitches []TimedSwitch  `yaml:"timedSwitches,omitempty"`
+}
+
+// DefaultKubeconfigBackups is the number of backups kept for each kubeconfig file when not configured
+const DefaultKubeconfigBackups = 10
+
+// Kubeconfig configures how kz modifies kubeconfig files
+type Kubeconfig struct {
+	// Backups is the number of backups kept for each kubeconfig file modified by kz, zero disables backups
+	Backups *int `yaml:"backups,omitempty"`
+	// StateFile is a writable kubeconfig file receiving current context and namespace overrides instead of the files defining them
+	StateFile string `yaml:"stateFile,omitempty"`
+}
+
+// SwitchHook runs shell commands before and after switching to contexts matching a glob pattern or having a tag
+type SwitchHook struct {
+	Match string `yaml:"match"`
+	Tag   string `yaml:"tag"`
+	Pre   string `yaml:"pre"`
+	Post  string `yaml:"post"`
+}
+
+// ContextEnv sets environment variables for contexts matching a glob pattern or having a tag
+type ContextEnv struct {
+	Match string            `yaml:"match"`
+	Tag   string            `yaml:"tag"`
+	Vars  map[string]string `yaml:"vars"`
+}
+
+// TimedSwitch records a time-limited switch that is reverted once it expires.
+// Session is the overlay of the session the switch was made in, empty when the switch was made outside of sessions
+type TimedSwitch struct {
+	Session           string    `yaml:"session"`
+	Context           string    `yaml:"context"`
+	PreviousContext   string    `yaml:"previousContext"`
+	PreviousNamespace string    `yaml:"previousNamespace"`
+	ExpiresAt         time.Time `yaml:"expiresAt"`
+}
+
+// Prompt customizes how contexts are displayed by `kz prompt`
+type Prompt struct {
+	Contexts []PromptContext `yaml:"contexts"`
+}
+
+// PromptContext sets display name and color of contexts matching a glob pattern
+type PromptContext struct {
+	Match string `yaml:"match"`
+	Name  string `yaml:"name"`
+	Color string `yaml:"color"`
+}
+
+// HistoryEntry records how many times and the last time a context/namespace combination was switched to.
+// Namespace is empty when only the context was switched
+type HistoryEntry struct {
+	Context   string    `yaml:"context"`
+	Namespace string    `yaml:"namespace"`
+	Count     int       `yaml:"count"`
+	LastUsed  time.Time `yaml:"lastUsed"`
+}
+
+// Combination is a pair of tracked context and tracked namespace
+type Combination struct {
+	Context   string
+	Namespace string
 }
 
 func (c *Config) AddNamespaces(namespaces ...string) {
@@ -53,60 +142,568 @@ func (c *Config) NamespacesMatching(query string) []string {
 	return namespaces
 }
 
+// ContextsSelectedBy returns tracked contexts selected by the given selector, which is one of:
+// `@tag` for contexts matching any glob pattern of the tag, a glob pattern, or a substring query
+func (c *Config) ContextsSelectedBy(selector string) ([]string, error) {
+	if tag, ok := strings.CutPrefix(selector, "@"); ok {
+		patterns, ok := c.Tags[tag]
+		if !ok {
+			return nil, fmt.Errorf("tag '%s' is not defined", tag)
+		}
+
+		return c.contextsMatchingGlobs(patterns...)
+	}
+
+	if strings.ContainsAny(selector, "*?[") {
+		return c.contextsMatchingGlobs(selector)
+	}
+
+	return c.ContextsMatching(selector), nil
+}
+
+func (c *Config) contextsMatchingGlobs(patterns ...string) ([]string, error) {
+	var globs []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := CompileGlob(p)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, re)
+	}
+
+	var contexts []string
+	for _, ctx := range c.Contexts {
+		if slices.ContainsFunc(globs, func(re *regexp.Regexp) bool { return re.MatchString(ctx) }) {
+			contexts = append(contexts, ctx)
+		}
+	}
+	return contexts, nil
+}
+
+func (c *Config) RecordSwitch(context string, namespace string, at time.Time) {
+	for i, h := range c.History {
+		if h.Context == context && h.Namespace == namespace {
+			c.History[i].Count++
+			c.History[i].LastUsed = at
+			return
+		}
+	}
+
+	c.History = append(c.History, HistoryEntry{
+		Context:   context,
+		Namespace: namespace,
+		Count:     1,
+		LastUsed:  at,
+	})
+}
+
+// ContextsByRecency returns tracked contexts, most recently used first.
+// Contexts that have never been switched to keep their tracked order
+func (c *Config) ContextsByRecency() []string {
+	return sortedByRecency(c.Contexts, c.contextLastUsed)
+}
+
+// NamespacesByRecency returns tracked namespaces, most recently used first.
+// Namespaces that have never been switched to keep their tracked order
+func (c *Config) NamespacesByRecency() []string {
+	return sortedByRecency(c.Namespaces, c.namespaceLastUsed)
+}
+
+// CombinationsByRecency returns every tracked context/namespace combination, most recently used first.
+// Combinations that have never been switched to are ordered by recency of their context
+func (c *Config) CombinationsByRecency() []Combination {
+	var combinations []Combination
+	for _, ctx := range c.Contexts {
+		for _, ns := range c.Namespaces {
+			combinations = append(combinations, Combination{
+				Context:   ctx,
+				Namespace: ns,
+			})
+		}
+	}
+
+	sort.SliceStable(combinations, func(i, j int) bool {
+		left, right := c.combinationLastUsed(combinations[i]), c.combinationLastUsed(combinations[j])
+		if !left.Equal(right) {
+			return left.After(right)
+		}
+
+		return c.contextLastUsed(combinations[i].Context).After(c.contextLastUsed(combinations[j].Context))
+	})
+
+	return combinations
+}
+
+// ContextsByFrecency returns tracked contexts ordered by frecency, i.e. how often and how recently they were switched to.
+// Contexts that have never been switched to keep their tracked order
+func (c *Config) ContextsByFrecency(now time.Time) []string {
+	return sortedByFrecency(c.Contexts, func(ctx string) float64 {
+		return c.frecency(now, func(h HistoryEntry) bool { return h.Context == ctx })
+	})
+}
+
+// NamespacesByFrecency returns tracked namespaces ordered by frecency, i.e. how often and how recently they were switched to.
+// Namespaces that have never been switched to keep their tracked order
+func (c *Config) NamespacesByFrecency(now time.Time) []string {
+	return sortedByFrecency(c.Namespaces, func(ns string) float64 {
+		return c.frecency(now, func(h HistoryEntry) bool { return h.Namespace == ns })
+	})
+}
+
+// frecency sums use counts of matching history entries, weighted by how long ago they were last used
+func (c *Config) frecency(now time.Time, matches func(HistoryEntry) bool) float64 {
+	var score float64
+	for _, h := range c.History {
+		if !matches(h) {
+			continue
+		}
+
+		count := float64(max(h.Count, 1))
+		age := now.Sub(h.LastUsed)
+		switch {
+		case age < time.Hour:
+			score += count * 4
+		case age < 24*time.Hour:
+			score += count * 2
+		case age < 7*24*time.Hour:
+			score += count * 0.5
+		default:
+			score += count * 0.25
+		}
+	}
+	return score
+}
+
+// HistoryByRecency returns history entries, most recently used first
+func (c *Config) HistoryByRecency() []HistoryEntry {
+	sorted := slices.Clone(c.History)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastUsed.After(sorted[j].LastUsed)
+	})
+	return sorted
+}
+
+func (c *Config) contextLastUsed(context string) time.Time {
+	var lastUsed time.Time
+	for _, h := range c.History {
+		if h.Context == context && h.LastUsed.After(lastUsed) {
+			lastUsed = h.LastUsed
+		}
+	}
+	return lastUsed
+}
+
+func (c *Config) namespaceLastUsed(namespace string) time.Time {
+	var lastUsed time.Time
+	for _, h := range c.History {
+		if h.Namespace == namespace && h.LastUsed.After(lastUsed) {
+			lastUsed = h.LastUsed
+		}
+	}
+	return lastUsed
+}
+
+func (c *Config) combinationLastUsed(combination Combination) time.Time {
+	for _, h := range c.History {
+		if h.Context == combination.Context && h.Namespace == combination.Namespace {
+			return h.LastUsed
+		}
+	}
+	return time.Time{}
+}
+
+func sortedByFrecency(items []string, frecency func(string) float64) []string {
+	sorted := slices.Clone(items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return frecency(sorted[i]) > frecency(sorted[j])
+	})
+	return sorted
+}
+
+func sortedByRecency(items []string, lastUsed func(string) time.Time) []string {
+	sorted := slices.Clone(items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return lastUsed(sorted[i]).After(lastUsed(sorted[j]))
+	})
+	return sorted
+}
+
+// HasTag reports whether the context matches any glob pattern of the tag
+func (c *Config) HasTag(context string, tag string) bool {
+	return slices.ContainsFunc(c.Tags[tag], func(pattern string) bool {
+		return MatchGlob(pattern, context)
+	})
+}
+
+// SwitchHooksFor returns hooks matching the context by glob pattern or by tag, in configured order
+func (c *Config) SwitchHooksFor(context string) []SwitchHook {
+	var hooks []SwitchHook
+	for _, h := range c.Hooks {
+		if c.matchesContext(context, h.Match, h.Tag) {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks
+}
+
+// EnvFor returns environment variables of the context, variables from later matching rules override earlier ones
+func (c *Config) EnvFor(context string) map[string]string {
+	vars := make(map[string]string)
+	for _, e := range c.Env {
+		if c.matchesContext(context, e.Match, e.Tag) {
+			for name, value := range e.Vars {
+				vars[name] = value
+			}
+		}
+	}
+	return vars
+}
+
+// EnvNames returns sorted names of environment variables of all contexts
+func (c *Config) EnvNames() []string {
+	var names []string
+	for _, e := range c.Env {
+		for name := range e.Vars {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (c *Config) matchesContext(context string, pattern string, tag string) bool {
+	return (len(pattern) > 0 && MatchGlob(pattern, context)) || (len(tag) > 0 && c.HasTag(context, tag))
+}
+
+// KubeconfigBackups returns the number of backups kept for each kubeconfig file modified by kz
+func (c *Config) KubeconfigBackups() int {
+	if c.Kubeconfig.Backups == nil {
+		return DefaultKubeconfigBackups
+	}
+
+	return max(*c.Kubeconfig.Backups, 0)
+}
+
+// KubeconfigStateFile returns absolute path of the designated kubeconfig state file, or empty string when it's not designated.
+// Environment variables are expanded, and relative paths, including ones starting with `~/`, are relative to the home directory
+func (c *Config) KubeconfigStateFile() (string, error) {
+	path := os.ExpandEnv(c.Kubeconfig.StateFile)
+	if len(path) == 0 || filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user home directory: %v", err)
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~/")), nil
+}
+
+// IsProtected reports whether the context matches any of protected patterns
+func (c *Config) IsProtected(context string) bool {
+	return slices.ContainsFunc(c.Protected, func(pattern string) bool {
+		return MatchGlob(pattern, context)
+	})
+}
+
+// TimedSwitchFor returns the time-limited switch made in the given session, if any
+func (c *Config) TimedSwitchFor(session string) (TimedSwitch, bool) {
+	for _, t := range c.TimedSwitches {
+		if t.Session == session {
+			return t, true
+		}
+	}
+
+	return TimedSwitch{}, false
+}
+
+// SetTimedSwitch replaces the time-limited switch of the given session, a nil switch removes it
+func (c *Config) SetTimedSwitch(session string, timed *TimedSwitch) {
+	c.TimedSwitches = slices.DeleteFunc(c.TimedSwitches, func(t TimedSwitch) bool {
+		return t.Session == session
+	})
+
+	if timed != nil {
+		timed.Session = session
+		c.TimedSwitches = append(c.TimedSwitches, *timed)
+	}
+}
+
+// PromptContextFor returns prompt settings of the first rule matching given context
+func (c *Config) PromptContextFor(context string) (PromptContext, bool) {
+	for _, p := range c.Prompt.Contexts {
+		if MatchGlob(p.Match, context) {
+			return p, true
+		}
+	}
+
+	return PromptContext{}, false
+}
+
+// LoadFromDefaultLocation loads configuration merged from all layers and state from their default locations,
+// migrating configuration of previous versions first when needed. Use UpdateAtDefaultLocation to modify configuration
 func LoadFromDefaultLocation() (*Config, error) {
-	location, err := defaultConfigLocation()
+	stateLocation, err := StateLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	// loading rewrites files migrated from previous versions, so it's locked like updating.
+	// Only the user layer is owned by kz, other layers are migrated in memory
+	var c *Config
+	if err := withUserLocation(func(location string) error {
+		if _, err := loadNode(location, true); err != nil {
+			return err
+		}
+
+		layers, err := Layers()
+		if err != nil {
+			return err
+		}
+
+		c, err = LoadLayers(layers)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	unlock, err := lock(stateLocation)
 	if err != nil {
 		return nil, err
 	}
+	defer unlock()
 
-	return Load(location)
+	s, err := loadState(stateLocation, true)
+	if err != nil {
+		return nil, err
+	}
+
+	c.History = s.History
+	c.TimedSwitches = s.TimedSwitches
+	return c, nil
 }
 
-func Load(location string) (*Config, error) {
-	content, err := os.ReadFile(location)
+// LoadStateFromDefaultLocation loads only state, i.e. history and time-limited switches, without touching any file.
+// It's cheap enough for hooks running on every prompt
+func LoadStateFromDefaultLocation() (*Config, error) {
+	location, err := StateLocation()
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return &Config{}, nil
-		}
+		return nil, err
+	}
 
-		return nil, fmt.Errorf("failed to load yaml config from location %s: %v", location, err)
+	s, err := loadState(location, false)
+	if err != nil {
+		return nil, err
 	}
 
+	return &Config{History: s.History, TimedSwitches: s.TimedSwitches}, nil
+}
+
+// Load loads configuration without state. Configuration of previous versions is migrated in memory only
+func Load(location string) (*Config, error) {
 	var c Config
-	if err := yaml.Unmarshal(content, &c); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal yaml config from location %s: %v", location, err)
+	if err := loadYaml(location, &c, false); err != nil {
+		return nil, err
 	}
 
 	return &c, nil
 }
 
-func SaveToDefaultLocation(c *Config) error {
-	location, err := defaultConfigLocation()
+func loadState(location string, rewrite bool) (*state, error) {
+	var s state
+	if err := loadYaml(location, &s, rewrite); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// loadYaml leaves v empty when the file doesn't exist
+func loadYaml(location string, v any, rewrite bool) error {
+	node, err := loadNode(location, rewrite)
 	if err != nil {
 		return err
 	}
 
-	return Save(location, c)
+	if node == nil {
+		return nil
+	}
+
+	if err := node.Decode(v); err != nil {
+		return fmt.Errorf("failed to unmarshal yaml config from location %s: %v", location, err)
+	}
+
+	return nil
 }
 
-func Save(location string, c *Config) error {
-	marshalled, err := yaml.Marshal(c)
+// UpdateAtDefaultLocation applies update to the user layer of configuration and saves it, keeping comments,
+// ordering and keys unknown to kz. The file is locked while it's being updated so that concurrent kz processes don't overwrite each other's changes
+func UpdateAtDefaultLocation(update func(c *Config) error) error {
+	return withUserLocation(func(location string) error {
+		return Update(location, update)
+	})
+}
+
+// SetAtDefaultLocation sets the value of the key in the user layer of configuration, see Set
+func SetAtDefaultLocation(key string, value string) error {
+	return withUserLocation(func(location string) error {
+		return Set(location, key, value)
+	})
+}
+
+// ReplaceAtDefaultLocation replaces the user layer of configuration with edited content.
+// original is the content edited content was based on, replacing fails when the file has been changed since by another process
+func ReplaceAtDefaultLocation(original []byte, edited []byte) error {
+	return withUserLocation(func(location string) error {
+		current, err := os.ReadFile(location)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load yaml config from location %s: %v", location, err)
+		}
+
+		if !bytes.Equal(current, original) {
+			return fmt.Errorf("%s was changed by another process while being edited", location)
+		}
+
+		return writeFile(location, edited)
+	})
+}
+
+// withUserLocation calls f with location of the user layer of configuration, locked and migrated from the legacy location when needed
+func withUserLocation(f func(location string) error) error {
+	location, err := DefaultLocation()
 	if err != nil {
-		return fmt.Errorf("failed to marshal config to yaml: %v", err)
+		return err
 	}
 
-	if err := os.WriteFile(location, marshalled, 0644); err != nil {
-		return fmt.Errorf("failed to write config to yaml file at %s: %v", location, err)
+	stateLocation, err := StateLocation()
+	if err != nil {
+		return err
 	}
 
+	unlock, err := lock(location)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if len(os.Getenv(LocationEnvVar)) == 0 {
+		if err := migrateLegacy(location, stateLocation); err != nil {
+			return err
+		}
+	}
+
+	return f(location)
+}
+
+// UpdateStateAtDefaultLocation applies update to the latest state, i.e. history and time-limited switches, and saves it without touching configuration.
+// State of c is replaced with the updated state
+func UpdateStateAtDefaultLocation(c *Config, update func(s *Config)) error {
+	location, err := StateLocation()
+	if err != nil {
+		return err
+	}
+
+	return updateState(location, c, update)
+}
+
+// updateState reloads state under lock before applying update, so that changes made by other kz processes since c was loaded,
+// e.g. by prompt hooks in other terminals, are not lost
+func updateState(location string, c *Config, update func(s *Config)) error {
+	unlock, err := lock(location)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := loadState(location, true)
+	if err != nil {
+		return err
+	}
+
+	latest := &Config{
+		History:       s.History,
+		TimedSwitches: s.TimedSwitches,
+	}
+	update(latest)
+
+	if err := saveState(location, latest); err != nil {
+		return err
+	}
+
+	c.History = latest.History
+	c.TimedSwitches = latest.TimedSwitches
 	return nil
 }
 
-func defaultConfigLocation() (string, error) {
-	home, err := os.UserHomeDir()
+// Save saves configuration without state in the current version
+func Save(location string, c *Config) error {
+	versioned := *c
+	versioned.Version = currentVersion()
+	return saveYaml(location, versioned)
+}
+
+func saveState(location string, c *Config) error {
+	return saveYaml(location, state{
+		Version:       currentVersion(),
+		History:       c.History,
+		TimedSwitches: c.TimedSwitches,
+	})
+}
+
+func saveYaml(location string, v any) error {
+	marshalled, err := yaml.Marshal(v)
 	if err != nil {
-		return "", fmt.Errorf("failed to determine user home directory: %v", err)
+		return fmt.Errorf("failed to marshal config to yaml: %v", err)
+	}
+
+	return writeFile(location, marshalled)
+}
+
+// writeFile replaces the file atomically by renaming a fully written temporary file over it, so that readers never see a partial file.
+// Symlinked files, e.g. from a dotfiles repository, are written through the link
+func writeFile(location string, content []byte) error {
+	mode := os.FileMode(0644)
+	if resolved, err := filepath.EvalSymlinks(location); err == nil {
+		location = resolved
+		if info, err := os.Stat(location); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(location), 0755); err != nil {
+		return fmt.Errorf("failed to create directory of %s: %v", location, err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(location), "."+filepath.Base(location)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %v", location, err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write config to yaml file at %s: %v", location, err)
 	}
 
-	return path.Join(home, ".kz.yml"), nil
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write config to yaml file at %s: %v", location, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write config to yaml file at %s: %v", location, err)
+	}
+
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %v", location, err)
+	}
+
+	if err := os.Rename(f.Name(), location); err != nil {
+		return fmt.Errorf("failed to replace config file at %s: %v", location, err)
+	}
+
+	return nil
 }
@@ -76,6 +76,198 @@ func TestConfig(t *testing.T) {
 
 		require.Equal(t, []string{"ns2"}, namespaces)
 	})
+
+	t.Run("update last used time when recording a combination that was switched to before", func(t *testing.T) {
+		c := Config{}
+		first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		second := first.Add(time.Hour)
+
+		c.RecordSwitch("context1", "ns1", first)
+		c.RecordSwitch("context1", "ns1", second)
+
+		require.Equal(t, []HistoryEntry{
+			{Context: "context1", Namespace: "ns1", Count: 2, LastUsed: second},
+		}, c.History)
+	})
+
+	t.Run("return contexts and namespaces ordered by recency", func(t *testing.T) {
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		c := Config{
+			Contexts:   []string{"context1", "context2", "context3"},
+			Namespaces: []string{"ns1", "ns2", "ns3"},
+			History: []HistoryEntry{
+				{Context: "context2", Namespace: "", LastUsed: now.Add(-time.Hour)},
+				{Context: "context3", Namespace: "ns2", LastUsed: now},
+			},
+		}
+
+		require.Equal(t, []string{"context3", "context2", "context1"}, c.ContextsByRecency())
+		require.Equal(t, []string{"ns2", "ns1", "ns3"}, c.NamespacesByRecency())
+	})
+
+	t.Run("return contexts and namespaces ordered by frecency", func(t *testing.T) {
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		c := Config{
+			Contexts:   []string{"context1", "context2", "context3"},
+			Namespaces: []string{"ns1", "ns2", "ns3"},
+			History: []HistoryEntry{
+				{Context: "context2", Namespace: "ns1", Count: 1, LastUsed: now.Add(-time.Minute)},
+				{Context: "context3", Namespace: "ns2", Count: 20, LastUsed: now.Add(-30 * 24 * time.Hour)},
+				{Context: "context3", Namespace: "ns3", Count: 1, LastUsed: now.Add(-30 * 24 * time.Hour)},
+			},
+		}
+
+		require.Equal(t, []string{"context3", "context2", "context1"}, c.ContextsByFrecency(now))
+		require.Equal(t, []string{"ns2", "ns1", "ns3"}, c.NamespacesByFrecency(now))
+	})
+
+	t.Run("return combinations ordered by recency of combination then recency of context", func(t *testing.T) {
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		c := Config{
+			Contexts:   []string{"context1", "context2"},
+			Namespaces: []string{"ns1", "ns2"},
+			History: []HistoryEntry{
+				{Context: "context2", Namespace: "", LastUsed: now},
+				{Context: "context1", Namespace: "ns2", LastUsed: now.Add(-time.Hour)},
+			},
+		}
+
+		require.Equal(t, []Combination{
+			{Context: "context1", Namespace: "ns2"},
+			{Context: "context2", Namespace: "ns1"},
+			{Context: "context2", Namespace: "ns2"},
+			{Context: "context1", Namespace: "ns1"},
+		}, c.CombinationsByRecency())
+	})
+
+	t.Run("select contexts by tag, glob pattern or substring query", func(t *testing.T) {
+		c := Config{
+			Contexts: []string{"eu-prod", "eu-staging", "us-prod", "us-staging"},
+			Tags: map[string][]string{
+				"prod": {"*-prod"},
+			},
+		}
+
+		tagged, err := c.ContextsSelectedBy("@prod")
+		require.NoError(t, err)
+		require.Equal(t, []string{"eu-prod", "us-prod"}, tagged)
+
+		globbed, err := c.ContextsSelectedBy("eu-*")
+		require.NoError(t, err)
+		require.Equal(t, []string{"eu-prod", "eu-staging"}, globbed)
+
+		queried, err := c.ContextsSelectedBy("staging")
+		require.NoError(t, err)
+		require.Equal(t, []string{"eu-staging", "us-staging"}, queried)
+	})
+
+	t.Run("return error when selecting contexts by undefined tag", func(t *testing.T) {
+		c := Config{Contexts: []string{"eu-prod"}}
+
+		_, err := c.ContextsSelectedBy("@prod")
+
+		require.ErrorContains(t, err, "tag 'prod' is not defined")
+	})
+
+	t.Run("protect contexts matching any protected pattern", func(t *testing.T) {
+		c := Config{Protected: []string{"*prod*", "arn:aws:eks:*:cluster/live"}}
+
+		require.True(t, c.IsProtected("eu-prod"))
+		require.True(t, c.IsProtected("arn:aws:eks:eu-west-1:123456789012:cluster/live"))
+		require.False(t, c.IsProtected("staging"))
+	})
+
+	t.Run("keep one time-limited switch per session", func(t *testing.T) {
+		expiresAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		c := Config{}
+
+		c.SetTimedSwitch("", &TimedSwitch{Context: "context1", ExpiresAt: expiresAt})
+		c.SetTimedSwitch("session-1", &TimedSwitch{Context: "context2", ExpiresAt: expiresAt})
+		c.SetTimedSwitch("", &TimedSwitch{Context: "context3", ExpiresAt: expiresAt})
+
+		timed, ok := c.TimedSwitchFor("")
+		require.True(t, ok)
+		require.Equal(t, "context3", timed.Context)
+		require.Len(t, c.TimedSwitches, 2)
+
+		c.SetTimedSwitch("session-1", nil)
+
+		_, ok = c.TimedSwitchFor("session-1")
+		require.False(t, ok)
+	})
+
+	t.Run("return switch hooks matching context by glob pattern or tag", func(t *testing.T) {
+		c := Config{
+			Tags: map[string][]string{
+				"eks": {"arn:aws:eks:*"},
+			},
+			Hooks: []SwitchHook{
+				{Tag: "eks", Pre: "aws sso login"},
+				{Match: "*prod*", Post: "echo prod"},
+				{Match: "gke_*", Pre: "gcloud config set project"},
+			},
+		}
+
+		hooks := c.SwitchHooksFor("arn:aws:eks:eu-west-1:123456789012:cluster/prod")
+
+		require.Equal(t, []SwitchHook{
+			{Tag: "eks", Pre: "aws sso login"},
+			{Match: "*prod*", Post: "echo prod"},
+		}, hooks)
+	})
+
+	t.Run("merge environment variables of matching rules, later rules override earlier ones", func(t *testing.T) {
+		c := Config{
+			Tags: map[string][]string{
+				"eks": {"arn:aws:eks:*"},
+			},
+			Env: []ContextEnv{
+				{Tag: "eks", Vars: map[string]string{"AWS_PROFILE": "default", "AWS_REGION": "eu-west-1"}},
+				{Match: "*prod", Vars: map[string]string{"AWS_PROFILE": "prod"}},
+				{Match: "gke_*", Vars: map[string]string{"CLOUDSDK_CORE_PROJECT": "project"}},
+			},
+		}
+
+		vars := c.EnvFor("arn:aws:eks:eu-west-1:123456789012:cluster/prod")
+
+		require.Equal(t, map[string]string{"AWS_PROFILE": "prod", "AWS_REGION": "eu-west-1"}, vars)
+		require.Equal(t, []string{"AWS_PROFILE", "AWS_REGION", "CLOUDSDK_CORE_PROJECT"}, c.EnvNames())
+	})
+}
+
+func TestKubeconfig(t *testing.T) {
+	t.Run("keep default number of kubeconfig backups when not configured", func(t *testing.T) {
+		c := &Config{}
+
+		require.Equal(t, DefaultKubeconfigBackups, c.KubeconfigBackups())
+	})
+
+	t.Run("disable kubeconfig backups when configured to zero", func(t *testing.T) {
+		backups := 0
+		c := &Config{Kubeconfig: Kubeconfig{Backups: &backups}}
+
+		require.Equal(t, 0, c.KubeconfigBackups())
+	})
+
+	t.Run("resolve kubeconfig state file relative to home directory", func(t *testing.T) {
+		t.Setenv("HOME", "/home/user")
+		t.Setenv("STATE_DIR", "/var/kz")
+
+		for file, expected := range map[string]string{
+			"":                         "",
+			"~/.kube/kz-state":         "/home/user/.kube/kz-state",
+			".kube/kz-state":           "/home/user/.kube/kz-state",
+			"$STATE_DIR/kubeconfig":    "/var/kz/kubeconfig",
+			"/etc/kubernetes/kz-state": "/etc/kubernetes/kz-state",
+		} {
+			c := &Config{Kubeconfig: Kubeconfig{StateFile: file}}
+
+			path, err := c.KubeconfigStateFile()
+
+			require.NoError(t, err)
+			require.Equal(t, expected, path, file)
+		}
+	})
 }
 
 func TestLoad(t *testing.T) {
@@ -91,6 +283,7 @@ func TestLoad(t *testing.T) {
 
 		require.NoError(t, err)
 		require.Equal(t, &Config{
+			Version: currentVersion(),
 			Contexts: []string{
 				"context1",
 				"context2",
@@ -127,5 +320,6 @@ func TestSave(t *testing.T) {
 		require.Contains(t, content, "context2")
 		require.Contains(t, content, "ns1")
 		require.Contains(t, content, "ns2")
+		require.Contains(t, content, "version: 1")
 	})
 }
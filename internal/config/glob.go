@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CompileGlob converts a glob pattern to a regular expression. Unlike path.Match, `*` also matches `/`
+// so that patterns work with context names like EKS ARNs. Supported syntax: `*`, `?` and `[...]` character classes
+func CompileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid glob pattern '%s': unclosed character class", pattern)
+			}
+
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern '%s': %v", pattern, err)
+	}
+
+	return re, nil
+}
+
+// MatchGlob reports whether name matches the glob pattern, invalid patterns never match
+func MatchGlob(pattern string, name string) bool {
+	re, err := CompileGlob(pattern)
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(name)
+}
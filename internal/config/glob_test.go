@@ -0,0 +1,29 @@
+//go:build unit
+
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	t.Run("match names using glob patterns", func(t *testing.T) {
+		require.True(t, MatchGlob("*prod*", "eu-prod-1"))
+		require.True(t, MatchGlob("arn:aws:eks:*:cluster/prod", "arn:aws:eks:eu-west-1:123456789012:cluster/prod"))
+		require.True(t, MatchGlob("eu-?", "eu-1"))
+		require.True(t, MatchGlob("eu-[12]", "eu-2"))
+		require.True(t, MatchGlob("eu-[!12]", "eu-3"))
+		require.False(t, MatchGlob("eu-*", "us-east-1"))
+		require.False(t, MatchGlob("prod", "eu-prod-1"))
+		require.False(t, MatchGlob("a.b", "axb"))
+	})
+
+	t.Run("return error when pattern is invalid", func(t *testing.T) {
+		_, err := CompileGlob("eu-[12")
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unclosed character class")
+		require.False(t, MatchGlob("eu-[12", "eu-1"))
+	})
+}
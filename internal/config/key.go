@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueAt returns the node at the key, which is a dot-separated path of mapping keys and sequence indexes, e.g. `tags.prod` or `hooks.0.pre`
+func ValueAt(root *yaml.Node, key string) (*yaml.Node, bool) {
+	node := root
+	for _, segment := range strings.Split(key, ".") {
+		switch node.Kind {
+		case yaml.MappingNode:
+			index := mappingIndex(node, segment)
+			if index < 0 {
+				return nil, false
+			}
+			node = node.Content[index+1]
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node.Content) {
+				return nil, false
+			}
+			node = node.Content[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return node, true
+}
+
+// Set sets the value at the key, see ValueAt, in configuration at location, keeping comments and ordering of the file.
+// The value is parsed as yaml so that lists and mappings can be set too, e.g. `["*prod*"]`.
+// The key must be part of configuration and the value must have the type of the key
+func Set(location string, key string, value string) error {
+	indexes, err := validateKey(key)
+	if err != nil {
+		return err
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("failed to parse value '%s' of key '%s': %v", value, key, err)
+	}
+
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+	if len(parsed.Content) > 0 {
+		valueNode = parsed.Content[0]
+	}
+
+	doc, indent, err := loadDocument(location, true)
+	if err != nil {
+		return err
+	}
+
+	if doc == nil {
+		doc = &yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+		setVersion(doc.Content[0], currentVersion())
+	}
+
+	if err := setNode(doc.Content[0], strings.Split(key, "."), indexes, "", valueNode); err != nil {
+		return fmt.Errorf("failed to set key '%s': %v", key, err)
+	}
+
+	if problems := validateTypes(location, doc.Content[0]); len(problems) > 0 {
+		return fmt.Errorf("invalid value '%s' of key '%s': %s", value, key, problems[0].Message)
+	}
+
+	return writeDocument(location, doc, indent)
+}
+
+// setNode sets value at the path of segments below node, creating missing mappings and lists on the way.
+// indexes tells for each segment whether it's an index of a list, path is the part of the key that has been walked so far
+func setNode(node *yaml.Node, segments []string, indexes []bool, path string, value *yaml.Node) error {
+	segment := segments[0]
+	if indexes[0] && node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("%s is not a list", strings.TrimSuffix(path, "."))
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		index := mappingIndex(node, segment)
+		if index < 0 {
+			child := value
+			if len(segments) > 1 && indexes[1] {
+				child = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			} else if len(segments) > 1 {
+				child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment}, child)
+			index = len(node.Content) - 2
+		}
+
+		if len(segments) == 1 {
+			node.Content[index+1] = withComments(value, node.Content[index+1])
+			return nil
+		}
+
+		return setNode(node.Content[index+1], segments[1:], indexes[1:], path+segment+".", value)
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(node.Content) {
+			return fmt.Errorf("index %s is out of range of %d items in %s", segment, len(node.Content), strings.TrimSuffix(path, "."))
+		}
+
+		if len(segments) == 1 {
+			node.Content[index] = withComments(value, node.Content[index])
+			return nil
+		}
+
+		return setNode(node.Content[index], segments[1:], indexes[1:], path+segment+".", value)
+	default:
+		return fmt.Errorf("'%s' can't be set in a value that is neither a mapping nor a list", segment)
+	}
+}
+
+// validateKey checks that the key refers to a part of configuration, settings of kz and state are not settable.
+// It returns for each segment of the key whether it's an index of a list
+func validateKey(key string) ([]bool, error) {
+	var indexes []bool
+	t := reflect.TypeOf(Config{})
+	for _, segment := range strings.Split(key, ".") {
+		if t == reflect.TypeOf(time.Time{}) || t == reflect.TypeOf(time.Duration(0)) {
+			return nil, fmt.Errorf("unknown key '%s'", key)
+		}
+
+		indexes = append(indexes, t.Kind() == reflect.Slice)
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByKey(t, segment)
+			if !ok || (t == reflect.TypeOf(Config{}) && segment == versionKey) {
+				return nil, fmt.Errorf("unknown key '%s'", key)
+			}
+			t = field.Type
+		case reflect.Slice:
+			if _, err := strconv.Atoi(segment); err != nil {
+				return nil, fmt.Errorf("unknown key '%s', items of lists are referred to by their index", key)
+			}
+			t = t.Elem()
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("unknown key '%s'", key)
+		}
+	}
+
+	return indexes, nil
+}
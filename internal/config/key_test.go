@@ -0,0 +1,117 @@
+//go:build unit
+
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValueAt(t *testing.T) {
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(`tags:
+  prod:
+    - "*prod*"
+hooks:
+  - match: "*"
+    pre: echo pre
+`), &doc))
+
+	t.Run("return value at path of mapping keys and list indexes", func(t *testing.T) {
+		value, ok := ValueAt(doc.Content[0], "hooks.0.pre")
+
+		require.True(t, ok)
+		require.Equal(t, "echo pre", value.Value)
+	})
+
+	t.Run("return false when key is not set", func(t *testing.T) {
+		_, ok := ValueAt(doc.Content[0], "tags.dev")
+		require.False(t, ok)
+
+		_, ok = ValueAt(doc.Content[0], "hooks.1")
+		require.False(t, ok)
+	})
+}
+
+func TestSet(t *testing.T) {
+	t.Run("set value keeping comments", func(t *testing.T) {
+		location := writeConfig(t, `version: 1
+# limit switches to production
+protectedMaxDuration: 15m # agreed with on-call
+`)
+
+		require.NoError(t, Set(location, "protectedMaxDuration", "30m"))
+
+		require.Equal(t, `version: 1
+# limit switches to production
+protectedMaxDuration: 30m # agreed with on-call
+`, readConfig(t, location))
+	})
+
+	t.Run("set lists and create missing mappings", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "config.yml")
+
+		require.NoError(t, Set(location, "tags.prod", `["*prod*", "*live*"]`))
+		require.NoError(t, Set(location, "protected", "[]"))
+
+		c, err := Load(location)
+		require.NoError(t, err)
+		require.Equal(t, map[string][]string{"prod": {"*prod*", "*live*"}}, c.Tags)
+		require.Equal(t, currentVersion(), c.Version)
+	})
+
+	t.Run("set item of a list by index", func(t *testing.T) {
+		location := writeConfig(t, `hooks:
+  - match: "*"
+    pre: echo pre
+`)
+
+		require.NoError(t, Set(location, "hooks.0.post", "echo post"))
+
+		c, err := Load(location)
+		require.NoError(t, err)
+		require.Equal(t, []SwitchHook{{Match: "*", Pre: "echo pre", Post: "echo post"}}, c.Hooks)
+	})
+
+	t.Run("return error for unknown keys", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "config.yml")
+
+		require.ErrorContains(t, Set(location, "protectd", "[]"), "unknown key 'protectd'")
+		require.ErrorContains(t, Set(location, "version", "3"), "unknown key 'version'")
+		require.ErrorContains(t, Set(location, "hooks.first.pre", "echo"), "items of lists are referred to by their index")
+		require.NoFileExists(t, location)
+	})
+
+	t.Run("return error when value has wrong type", func(t *testing.T) {
+		location := writeConfig(t, "protectedMaxDuration: 15m\n")
+
+		err := Set(location, "protectedMaxDuration", "soon")
+
+		require.ErrorContains(t, err, "invalid value 'soon' of key 'protectedMaxDuration'")
+		c, err := Load(location)
+		require.NoError(t, err)
+		require.Equal(t, 15*time.Minute, c.ProtectedMaxDuration)
+	})
+
+	t.Run("return error when list index is out of range", func(t *testing.T) {
+		location := writeConfig(t, "hooks: []\n")
+
+		require.ErrorContains(t, Set(location, "hooks.0.pre", "echo"), "index 0 is out of range of 0 items")
+	})
+
+	t.Run("return error when setting item of a list that is not set", func(t *testing.T) {
+		location := writeConfig(t, "version: 1\n")
+
+		require.ErrorContains(t, Set(location, "hooks.0.pre", "echo"), "index 0 is out of range of 0 items in hooks")
+		require.Equal(t, "version: 1\n", readConfig(t, location))
+	})
+
+	t.Run("return error when setting item of a value that is not a list", func(t *testing.T) {
+		location := writeConfig(t, "hooks:\n  first:\n    pre: echo\n")
+
+		require.ErrorContains(t, Set(location, "hooks.0.pre", "echo"), "hooks is not a list")
+	})
+}
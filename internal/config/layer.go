@@ -0,0 +1,259 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"slices"
+)
+
+// environment variables overriding locations of configuration layers
+const (
+	SystemLocationEnvVar = "KZ_SYSTEM_CONFIG"
+	TeamLocationEnvVar   = "KZ_TEAM_CONFIG"
+)
+
+const defaultSystemLocation = "/etc/kz/config.yml"
+
+// replaceTag marks a value that replaces the value from lower layers instead of being merged with it
+const replaceTag = "!replace"
+
+// Layer is one of configuration files merged into the effective configuration, in order of precedence from lowest to highest:
+// system, team and user. Only the user layer is written by kz
+type Layer struct {
+	Name     string
+	Location string
+}
+
+const (
+	SystemLayer = "system"
+	TeamLayer   = "team"
+	UserLayer   = "user"
+)
+
+// Layers returns configuration layers from lowest to highest precedence.
+// Team layer is only used when KZ_TEAM_CONFIG is set
+func Layers() ([]Layer, error) {
+	system := os.Getenv(SystemLocationEnvVar)
+	if len(system) == 0 {
+		system = defaultSystemLocation
+	}
+
+	layers := []Layer{{Name: SystemLayer, Location: system}}
+	if team := os.Getenv(TeamLocationEnvVar); len(team) > 0 {
+		layers = append(layers, Layer{Name: TeamLayer, Location: team})
+	}
+
+	user, err := DefaultLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(layers, Layer{Name: UserLayer, Location: user}), nil
+}
+
+// LoadLayers loads and merges configuration layers, missing layer files are skipped
+func LoadLayers(layers []Layer) (*Config, error) {
+	merged, err := MergeLayers(layers, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if merged == nil {
+		return &c, nil
+	}
+
+	if err := merged.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to decode merged config: %v", err)
+	}
+
+	// the same names can be listed by multiple layers
+	c.Contexts = unique(c.Contexts)
+	c.Namespaces = unique(c.Namespaces)
+	c.Protected = unique(c.Protected)
+	return &c, nil
+}
+
+// MergeLayers merges configuration layers into a single yaml node, or nil when none of the layer files exists.
+// Mappings are merged key by key, sequences from higher layers are appended to sequences from lower layers,
+// and scalars from higher layers replace the ones from lower layers. Values tagged with !replace replace values from lower layers as a whole.
+// When withOrigin is true, every scalar value is annotated with a line comment naming the layer it comes from
+func MergeLayers(layers []Layer, withOrigin bool) (*yaml.Node, error) {
+	var merged *yaml.Node
+	for _, l := range layers {
+		// layers are migrated in memory so that merging never writes, the user layer is rewritten under lock by LoadFromDefaultLocation
+		node, err := loadNode(l.Location, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if node == nil {
+			continue
+		}
+
+		if withOrigin {
+			annotateOrigin(node, l.Name)
+		}
+
+		merged = mergeNodes(merged, node)
+	}
+
+	if merged != nil {
+		clearReplaceTags(merged)
+	}
+
+	return merged, nil
+}
+
+// LowerLayersListing returns layers below the user layer whose list under the top-level key contains the value.
+// Such values stay in the effective configuration whatever the user layer lists, unless the user layer replaces the list with !replace
+func LowerLayersListing(layers []Layer, key string, value string) ([]Layer, error) {
+	var listing []Layer
+	for _, l := range layers {
+		node, err := loadNode(l.Location, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if node == nil {
+			continue
+		}
+
+		if l.Name == UserLayer {
+			if index := mappingIndex(node, key); index >= 0 && node.Content[index+1].Tag == replaceTag {
+				return nil, nil
+			}
+			continue
+		}
+
+		if slices.ContainsFunc(sequenceItems(node, key), func(n *yaml.Node) bool { return n.Value == value }) {
+			listing = append(listing, l)
+		}
+	}
+
+	return listing, nil
+}
+
+// loadNode returns the top-level node of the file migrated to current version, or nil when the file doesn't exist or is empty.
+// When rewrite is true and the file was migrated, the file is rewritten in the current version and the original is kept as a backup
+func loadNode(location string, rewrite bool) (*yaml.Node, error) {
+	doc, _, err := loadDocument(location, rewrite)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+
+	return doc.Content[0], nil
+}
+
+// loadDocument is like loadNode but returns the document node, which also holds comments at the top and the bottom of the file,
+// together with indentation used by the file
+func loadDocument(location string, rewrite bool) (*yaml.Node, int, error) {
+	content, err := os.ReadFile(location)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, defaultIndent, nil
+		}
+
+		return nil, 0, fmt.Errorf("failed to load yaml config from location %s: %v", location, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal yaml config from location %s: %v", location, err)
+	}
+
+	indent := indentOf(content)
+	if len(doc.Content) == 0 {
+		return nil, indent, nil
+	}
+
+	from, err := migrate(location, doc.Content[0])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if rewrite && from < currentVersion() {
+		if err := rewriteMigrated(location, content, from, &doc, indent); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return &doc, indent, nil
+}
+
+func mergeNodes(base *yaml.Node, override *yaml.Node) *yaml.Node {
+	if base == nil || override.Tag == replaceTag || base.Kind != override.Kind {
+		return override
+	}
+
+	switch override.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(override.Content); i += 2 {
+			key, value := override.Content[i], override.Content[i+1]
+			if index := mappingIndex(base, key.Value); index >= 0 {
+				base.Content[index+1] = mergeNodes(base.Content[index+1], value)
+			} else {
+				base.Content = append(base.Content, key, mergeNodes(nil, value))
+			}
+		}
+		return base
+	case yaml.SequenceNode:
+		base.Content = append(base.Content, override.Content...)
+		return base
+	default:
+		return override
+	}
+}
+
+// clearReplaceTags lets tags of values marked with !replace be resolved from their content when decoding
+func clearReplaceTags(node *yaml.Node) {
+	if node.Tag == replaceTag {
+		node.Tag = ""
+	}
+
+	for _, n := range node.Content {
+		clearReplaceTags(n)
+	}
+}
+
+// mappingIndex returns index of the key node in the mapping, or -1 when the key doesn't exist
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// annotateOrigin adds a line comment naming the layer to every scalar value.
+// Line comments can't be put inside flow style collections, .e.g. `[a, b]`, so collections are switched to block style
+func annotateOrigin(node *yaml.Node, origin string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		node.Style &^= yaml.FlowStyle
+		for i := 1; i < len(node.Content); i += 2 {
+			annotateOrigin(node.Content[i], origin)
+		}
+	case yaml.SequenceNode:
+		node.Style &^= yaml.FlowStyle
+		for _, n := range node.Content {
+			annotateOrigin(n, origin)
+		}
+	case yaml.ScalarNode:
+		node.LineComment = origin
+	}
+}
+
+func unique(items []string) []string {
+	var result []string
+	for _, i := range items {
+		if !slices.Contains(result, i) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
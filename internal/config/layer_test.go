@@ -0,0 +1,163 @@
+//go:build unit
+
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadLayers(t *testing.T) {
+	t.Run("merge mappings, append lists and replace scalars from higher layers", func(t *testing.T) {
+		layers := writeLayers(t,
+			`namespaces:
+  - kube-system
+protected:
+  - "*prod*"
+protectedMaxDuration: 1h
+tags:
+  prod:
+    - "*prod*"
+`,
+			`namespaces:
+  - payments
+  - kube-system
+protectedMaxDuration: 15m
+tags:
+  eks:
+    - "arn:aws:eks:*"
+`)
+
+		c, err := LoadLayers(layers)
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"kube-system", "payments"}, c.Namespaces)
+		require.Equal(t, []string{"*prod*"}, c.Protected)
+		require.Equal(t, 15*time.Minute, c.ProtectedMaxDuration)
+		require.Equal(t, map[string][]string{
+			"prod": {"*prod*"},
+			"eks":  {"arn:aws:eks:*"},
+		}, c.Tags)
+	})
+
+	t.Run("replace values tagged with !replace", func(t *testing.T) {
+		layers := writeLayers(t,
+			`namespaces:
+  - kube-system
+tags:
+  prod:
+    - "*prod*"
+`,
+			`namespaces: !replace
+  - payments
+tags: !replace
+  live:
+    - "*live*"
+`)
+
+		c, err := LoadLayers(layers)
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"payments"}, c.Namespaces)
+		require.Equal(t, map[string][]string{"live": {"*live*"}}, c.Tags)
+	})
+
+	t.Run("skip missing layers", func(t *testing.T) {
+		layers := writeLayers(t, "namespaces:\n  - payments\n")
+		layers = append([]Layer{{Name: SystemLayer, Location: filepath.Join(t.TempDir(), "not-existing.yml")}}, layers...)
+
+		c, err := LoadLayers(layers)
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"payments"}, c.Namespaces)
+	})
+
+	t.Run("migrate user layer of older version in memory only", func(t *testing.T) {
+		registerMigration(t)
+		layers := writeLayers(t, "namespaces:\n  - payments\n")
+
+		c, err := LoadLayers(layers)
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"*prod*"}, c.Protected)
+		require.Equal(t, "namespaces:\n  - payments\n", readConfig(t, layers[0].Location))
+		require.NoFileExists(t, layers[0].Location+".v1.bak")
+	})
+}
+
+func TestMergeLayers(t *testing.T) {
+	t.Run("annotate values with their layer", func(t *testing.T) {
+		layers := writeLayers(t, "namespaces:\n  - kube-system\n", "namespaces:\n  - payments\n")
+
+		merged, err := MergeLayers(layers, true)
+		require.NoError(t, err)
+
+		out, err := yaml.Marshal(merged)
+		require.NoError(t, err)
+		require.Contains(t, string(out), "- kube-system # system")
+		require.Contains(t, string(out), "- payments # user")
+	})
+
+	t.Run("annotate values of flow style collections in block style", func(t *testing.T) {
+		layers := writeLayers(t, "namespaces: [kube-system]\n", "namespaces: [payments]\ntags: {prod: [\"*prod*\"]}\n")
+
+		merged, err := MergeLayers(layers, true)
+		require.NoError(t, err)
+
+		out, err := yaml.Marshal(merged)
+		require.NoError(t, err)
+		require.Equal(t, `version: 1 # user
+namespaces:
+    - kube-system # system
+    - payments # user
+tags:
+    prod:
+        - "*prod*" # user
+`, string(out))
+	})
+}
+
+func TestLowerLayersListing(t *testing.T) {
+	t.Run("return layers below user layer listing the value", func(t *testing.T) {
+		layers := writeLayers(t, "namespaces:\n  - kube-system\n", "namespaces:\n  - kube-system\n  - payments\n")
+
+		listing, err := LowerLayersListing(layers, "namespaces", "kube-system")
+		require.NoError(t, err)
+		require.Equal(t, layers[:1], listing)
+
+		listing, err = LowerLayersListing(layers, "namespaces", "payments")
+		require.NoError(t, err)
+		require.Empty(t, listing)
+	})
+
+	t.Run("return no layers when user layer replaces the list", func(t *testing.T) {
+		layers := writeLayers(t, "namespaces:\n  - kube-system\n", "namespaces: !replace\n  - payments\n")
+
+		listing, err := LowerLayersListing(layers, "namespaces", "kube-system")
+
+		require.NoError(t, err)
+		require.Empty(t, listing)
+	})
+}
+
+// writeLayers writes system layer and, when given, user layer
+func writeLayers(t *testing.T, contents ...string) []Layer {
+	dir := t.TempDir()
+	names := []string{SystemLayer, UserLayer}
+	if len(contents) == 1 {
+		names = []string{UserLayer}
+	}
+
+	var layers []Layer
+	for i, content := range contents {
+		location := filepath.Join(dir, names[i]+".yml")
+		require.NoError(t, os.WriteFile(location, []byte(strings.TrimSpace(content)+"\n"), 0644))
+		layers = append(layers, Layer{Name: names[i], Location: location})
+	}
+	return layers
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LocationEnvVar overrides location of kz configuration file
+const LocationEnvVar = "KZ_CONFIG"
+
+// DefaultLocation returns location of kz configuration file: KZ_CONFIG when set,
+// otherwise kz/config.yml in XDG_CONFIG_HOME, which defaults to ~/.config
+func DefaultLocation() (string, error) {
+	if location := os.Getenv(LocationEnvVar); len(location) > 0 {
+		return location, nil
+	}
+
+	return xdgLocation("XDG_CONFIG_HOME", ".config", "config.yml")
+}
+
+// StateLocation returns location of the file keeping state that changes with every switch, .e.g. history.
+// It's kz/state.yml in XDG_STATE_HOME, which defaults to ~/.local/state, so that configuration can be versioned without the state
+func StateLocation() (string, error) {
+	return xdgLocation("XDG_STATE_HOME", filepath.Join(".local", "state"), "state.yml")
+}
+
+// BackupsLocation returns the directory keeping backups of kubeconfig files modified by kz, kz/backups in XDG_STATE_HOME
+func BackupsLocation() (string, error) {
+	return xdgLocation("XDG_STATE_HOME", filepath.Join(".local", "state"), "backups")
+}
+
+// LegacyLocation returns location of configuration file of previous versions, containing both configuration and state
+func LegacyLocation() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine user home directory: %v", err)
+	}
+
+	return filepath.Join(home, ".kz.yml"), nil
+}
+
+// xdgLocation follows XDG base directory specification: relative paths in the environment variable are ignored
+func xdgLocation(envVar string, homeRelativeDefault string, name string) (string, error) {
+	dir := os.Getenv(envVar)
+	if len(dir) == 0 || !filepath.IsAbs(dir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine user home directory: %v", err)
+		}
+
+		dir = filepath.Join(home, homeRelativeDefault)
+	}
+
+	return filepath.Join(dir, "kz", name), nil
+}
+
+// migrateLegacy splits the legacy configuration file into configuration and state files when configuration file doesn't exist yet.
+// The legacy file is kept with .bak suffix. Callers hold the lock of location so that concurrent kz processes don't migrate twice
+func migrateLegacy(location string, stateLocation string) error {
+	if _, err := os.Stat(location); !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	legacy, err := LegacyLocation()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(legacy); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	s, err := loadState(legacy, false)
+	if err != nil {
+		return err
+	}
+
+	if err := updateState(stateLocation, &Config{}, func(latest *Config) {
+		latest.History = s.History
+		latest.TimedSwitches = s.TimedSwitches
+	}); err != nil {
+		return err
+	}
+
+	// configuration is copied as a document without state keys so that comments, formatting and unknown keys are kept
+	doc, indent, err := loadDocument(legacy, false)
+	if err != nil {
+		return err
+	}
+
+	if doc == nil {
+		if err := Save(location, &Config{}); err != nil {
+			return err
+		}
+	} else {
+		removeKey(doc.Content[0], "history")
+		removeKey(doc.Content[0], "timedSwitches")
+		if err := writeDocument(location, doc, indent); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(legacy, legacy+".bak"); err != nil {
+		return fmt.Errorf("failed to rename migrated config file %s: %v", legacy, err)
+	}
+
+	return nil
+}
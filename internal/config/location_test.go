@@ -0,0 +1,187 @@
+//go:build unit
+
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDefaultLocation(t *testing.T) {
+	t.Run("use KZ_CONFIG when set", func(t *testing.T) {
+		t.Setenv(LocationEnvVar, "/path/to/config.yml")
+
+		location, err := DefaultLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, "/path/to/config.yml", location)
+	})
+
+	t.Run("use XDG_CONFIG_HOME when set", func(t *testing.T) {
+		t.Setenv(LocationEnvVar, "")
+		t.Setenv("XDG_CONFIG_HOME", "/path/to/xdg")
+
+		location, err := DefaultLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, "/path/to/xdg/kz/config.yml", location)
+	})
+
+	t.Run("ignore relative XDG_CONFIG_HOME", func(t *testing.T) {
+		t.Setenv(LocationEnvVar, "")
+		t.Setenv("HOME", "/home/user")
+		t.Setenv("XDG_CONFIG_HOME", "relative")
+
+		location, err := DefaultLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, "/home/user/.config/kz/config.yml", location)
+	})
+}
+
+func TestStateLocation(t *testing.T) {
+	t.Run("default to local state directory in home directory", func(t *testing.T) {
+		t.Setenv("HOME", "/home/user")
+		t.Setenv("XDG_STATE_HOME", "")
+
+		location, err := StateLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, "/home/user/.local/state/kz/state.yml", location)
+	})
+}
+
+func TestLoadFromDefaultLocation(t *testing.T) {
+	t.Run("migrate legacy config file into config and state files", func(t *testing.T) {
+		home := setupHome(t)
+		legacy := filepath.Join(home, ".kz.yml")
+		require.NoError(t, os.WriteFile(legacy, []byte(`namespaces:
+  - ns1
+history:
+  - context: context1
+    namespace: ns1
+    count: 2
+    lastUsed: 2023-01-01T00:00:00Z
+`), 0644))
+
+		c, err := LoadFromDefaultLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"ns1"}, c.Namespaces)
+		require.Equal(t, []HistoryEntry{
+			{Context: "context1", Namespace: "ns1", Count: 2, LastUsed: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}, c.History)
+		require.NoFileExists(t, legacy)
+		require.FileExists(t, legacy+".bak")
+
+		configContent, err := os.ReadFile(filepath.Join(home, ".config", "kz", "config.yml"))
+		require.NoError(t, err)
+		require.NotContains(t, string(configContent), "history")
+
+		stateContent, err := os.ReadFile(filepath.Join(home, ".local", "state", "kz", "state.yml"))
+		require.NoError(t, err)
+		require.Contains(t, string(stateContent), "context1")
+	})
+
+	t.Run("keep comments and unknown keys of legacy config file when migrating it", func(t *testing.T) {
+		home := setupHome(t)
+		require.NoError(t, os.WriteFile(filepath.Join(home, ".kz.yml"), []byte(`# my namespaces
+namespaces:
+  - ns1 # payments
+history:
+  - context: context1
+    namespace: ns1
+    count: 2
+    lastUsed: 2023-01-01T00:00:00Z
+extra: keep-me
+`), 0644))
+
+		_, err := LoadFromDefaultLocation()
+
+		require.NoError(t, err)
+		configContent, err := os.ReadFile(filepath.Join(home, ".config", "kz", "config.yml"))
+		require.NoError(t, err)
+		require.Equal(t, `version: 1
+# my namespaces
+namespaces:
+  - ns1 # payments
+extra: keep-me
+`, string(configContent))
+	})
+
+	t.Run("migrate legacy config file once when loaded concurrently", func(t *testing.T) {
+		home := setupHome(t)
+		legacy := filepath.Join(home, ".kz.yml")
+		require.NoError(t, os.WriteFile(legacy, []byte(`namespaces:
+  - ns1
+`), 0644))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := LoadFromDefaultLocation()
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		require.FileExists(t, legacy+".bak")
+	})
+
+	t.Run("keep lock files out of config directory", func(t *testing.T) {
+		home := setupHome(t)
+		require.NoError(t, UpdateAtDefaultLocation(func(c *Config) error {
+			c.AddNamespaces("ns1")
+			return nil
+		}))
+
+		entries, err := os.ReadDir(filepath.Join(home, ".config", "kz"))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		locks, err := os.ReadDir(filepath.Join(home, ".local", "state", "kz", "locks"))
+		require.NoError(t, err)
+		require.Len(t, locks, 1)
+	})
+
+	t.Run("keep state out of config file when saving", func(t *testing.T) {
+		home := setupHome(t)
+		require.NoError(t, UpdateAtDefaultLocation(func(c *Config) error {
+			c.AddNamespaces("ns1")
+			return nil
+		}))
+		require.NoError(t, UpdateStateAtDefaultLocation(&Config{}, func(s *Config) {
+			s.RecordSwitch("context1", "ns1", time.Now())
+		}))
+
+		configContent, err := os.ReadFile(filepath.Join(home, ".config", "kz", "config.yml"))
+		require.NoError(t, err)
+		require.NotContains(t, string(configContent), "context1")
+
+		loaded, err := LoadFromDefaultLocation()
+		require.NoError(t, err)
+		require.Equal(t, []string{"ns1"}, loaded.Namespaces)
+		require.Len(t, loaded.History, 1)
+	})
+}
+
+func setupHome(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(LocationEnvVar, "")
+	t.Setenv(SystemLocationEnvVar, filepath.Join(home, "system.yml"))
+	t.Setenv(TeamLocationEnvVar, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	return home
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// LocksLocation returns the directory keeping lock files of files updated by kz, kz/locks in XDG_STATE_HOME
+func LocksLocation() (string, error) {
+	return xdgLocation("XDG_STATE_HOME", filepath.Join(".local", "state"), "locks")
+}
+
+// lock takes an exclusive advisory lock guarding read-modify-write of the file at location, blocking until it's available.
+// The lock is held on a separate file in LocksLocation since the file itself is replaced on every write, and it shouldn't clutter
+// directories like ~/.config/kz that are often kept in dotfiles repositories.
+// Locks are released by the returned function or when the process exits
+func lock(location string) (func(), error) {
+	lockLocation, err := lockLocationOf(location)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(lockLocation), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory of lock file %s: %v", lockLocation, err)
+	}
+
+	f, err := os.OpenFile(lockLocation, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file of %s: %v", location, err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %v", location, err)
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// lockLocationOf names the lock file after the base name of the locked file, followed by a hash of its absolute path
+// so that files with the same name in different directories, e.g. KZ_CONFIG pointing to another config.yml, don't share a lock
+func lockLocationOf(location string) (string, error) {
+	absolute, err := filepath.Abs(location)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine absolute path of %s: %v", location, err)
+	}
+
+	dir, err := LocksLocation()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(absolute))
+	return filepath.Join(dir, fmt.Sprintf("%s-%x.lock", filepath.Base(absolute), sum[:8])), nil
+}
@@ -0,0 +1,187 @@
+//go:build unit
+
+package config
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// helperStateEnvVar makes the test binary act as a kz process updating the state file it points to
+const helperStateEnvVar = "KZ_TEST_HELPER_STATE"
+
+// helperSaveEnvVar makes the test binary act as a kz process saving the configuration file it points to
+const helperSaveEnvVar = "KZ_TEST_HELPER_SAVE"
+
+const updatesPerWorker = 20
+
+func TestSaveConcurrently(t *testing.T) {
+	t.Run("never leave partially written file", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "config.yml")
+		require.NoError(t, Save(location, &Config{Namespaces: []string{"ns0"}}))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 100)
+		for i := 0; i < 50; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				errs <- Save(location, &Config{Namespaces: []string{fmt.Sprintf("ns%d", i)}})
+			}(i)
+			go func() {
+				defer wg.Done()
+				c, err := Load(location)
+				if err == nil && len(c.Namespaces) != 1 {
+					err = fmt.Errorf("expected one namespace but got %v", c.Namespaces)
+				}
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(location))
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "temporary files should be cleaned up")
+	})
+
+	t.Run("never leave partially written file when saved by several processes", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "config.yml")
+		require.NoError(t, Save(location, &Config{Namespaces: []string{"ns0"}}))
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				cmd := exec.Command(os.Args[0], "-test.run=^TestSaveHelperProcess$")
+				cmd.Env = append(os.Environ(), helperSaveEnvVar+"="+location)
+				if output, err := cmd.CombinedOutput(); err != nil {
+					errs <- fmt.Errorf("helper process failed: %v\n%s", err, output)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < updatesPerWorker; j++ {
+					c, err := Load(location)
+					if err == nil && len(c.Namespaces) != 1 {
+						err = fmt.Errorf("expected one namespace but got %v", c.Namespaces)
+					}
+					if err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		entries, err := os.ReadDir(filepath.Dir(location))
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "temporary files should be cleaned up")
+	})
+
+	t.Run("write through symlink", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "dotfiles-config.yml")
+		location := filepath.Join(dir, "config.yml")
+		require.NoError(t, os.WriteFile(target, nil, 0600))
+		require.NoError(t, os.Symlink(target, location))
+
+		require.NoError(t, Save(location, &Config{Namespaces: []string{"ns1"}}))
+
+		info, err := os.Lstat(location)
+		require.NoError(t, err)
+		require.Equal(t, os.ModeSymlink, info.Mode().Type())
+		c, err := Load(target)
+		require.NoError(t, err)
+		require.Equal(t, []string{"ns1"}, c.Namespaces)
+		targetInfo, err := os.Stat(target)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), targetInfo.Mode().Perm())
+	})
+}
+
+func TestUpdateStateConcurrently(t *testing.T) {
+	t.Run("keep updates from all goroutines and processes", func(t *testing.T) {
+		t.Setenv("XDG_STATE_HOME", t.TempDir())
+		location := filepath.Join(t.TempDir(), "state.yml")
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				errs <- recordSwitches(location)
+			}()
+			go func() {
+				defer wg.Done()
+				cmd := exec.Command(os.Args[0], "-test.run=^TestUpdateStateHelperProcess$")
+				cmd.Env = append(os.Environ(), helperStateEnvVar+"="+location)
+				if output, err := cmd.CombinedOutput(); err != nil {
+					errs <- fmt.Errorf("helper process failed: %v\n%s", err, output)
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		s, err := loadState(location, false)
+		require.NoError(t, err)
+		require.Len(t, s.History, 1)
+		require.Equal(t, 8*updatesPerWorker, s.History[0].Count)
+	})
+}
+
+func TestSaveHelperProcess(t *testing.T) {
+	location := os.Getenv(helperSaveEnvVar)
+	if len(location) == 0 {
+		return
+	}
+
+	for i := 0; i < updatesPerWorker; i++ {
+		require.NoError(t, Save(location, &Config{Namespaces: []string{fmt.Sprintf("ns%d-%d", os.Getpid(), i)}}))
+	}
+}
+
+func TestUpdateStateHelperProcess(t *testing.T) {
+	location := os.Getenv(helperStateEnvVar)
+	if len(location) == 0 {
+		return
+	}
+
+	require.NoError(t, recordSwitches(location))
+}
+
+func recordSwitches(location string) error {
+	for i := 0; i < updatesPerWorker; i++ {
+		if err := updateState(location, &Config{}, func(s *Config) {
+			s.RecordSwitch("context1", "ns1", time.Now())
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strconv"
+)
+
+const versionKey = "version"
+
+// migration upgrades the top-level mapping of a file from the previous version to the next one
+type migration struct {
+	description string
+	migrate     func(root *yaml.Node)
+}
+
+// migrations[i] upgrades files of version i+1 to version i+2, new migrations are appended whenever the shape of existing keys changes.
+// Files without version key are version 1, i.e. `contexts` and `namespaces` lists written to ~/.kz.yml by previous releases,
+// keys added since then don't need migrations
+var migrations []migration
+
+// currentVersion is the schema version of configuration and state files written by this version of kz
+func currentVersion() int {
+	return len(migrations) + 1
+}
+
+// migrate upgrades the top-level node of the file at location to current version in place and returns the version it had.
+// Files newer than current version can't be downgraded and are rejected
+func migrate(location string, root *yaml.Node) (int, error) {
+	if root.Kind != yaml.MappingNode {
+		return currentVersion(), nil
+	}
+
+	version, err := versionOf(location, root)
+	if err != nil {
+		return 0, err
+	}
+
+	if version > currentVersion() {
+		return 0, fmt.Errorf("%s has version %d which is newer than version %d supported by this kz, upgrade kz to use it", location, version, currentVersion())
+	}
+
+	for _, m := range migrations[version-1:] {
+		m.migrate(root)
+	}
+
+	setVersion(root, currentVersion())
+	return version, nil
+}
+
+func versionOf(location string, root *yaml.Node) (int, error) {
+	index := mappingIndex(root, versionKey)
+	if index < 0 {
+		return 1, nil
+	}
+
+	value := root.Content[index+1]
+	version, err := strconv.Atoi(value.Value)
+	if err != nil || value.Kind != yaml.ScalarNode || version < 1 {
+		return 0, fmt.Errorf("invalid version '%s' in %s at line %d, version must be a positive number", value.Value, location, value.Line)
+	}
+
+	return version, nil
+}
+
+// setVersion sets version key of the mapping, adding it as the first key when missing
+func setVersion(root *yaml.Node, version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	if index := mappingIndex(root, versionKey); index >= 0 {
+		root.Content[index+1] = value
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: versionKey}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// removeKey removes the key and its value from the mapping, if any
+func removeKey(mapping *yaml.Node, key string) {
+	if mapping.Kind != yaml.MappingNode {
+		return
+	}
+
+	if index := mappingIndex(mapping, key); index >= 0 {
+		mapping.Content = append(mapping.Content[:index], mapping.Content[index+2:]...)
+	}
+}
+
+// sequenceItems returns items of the sequence under the key of the mapping, if any
+func sequenceItems(mapping *yaml.Node, key string) []*yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	index := mappingIndex(mapping, key)
+	if index < 0 || mapping.Content[index+1].Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	return mapping.Content[index+1].Content
+}
+
+// rewriteMigrated keeps original content of the file migrated from the given version as a backup next to it,
+// then replaces the file with the migrated document
+func rewriteMigrated(location string, original []byte, from int, doc *yaml.Node, indent int) error {
+	backup := fmt.Sprintf("%s.v%d.bak", location, from)
+	if err := os.WriteFile(backup, original, 0644); err != nil {
+		return fmt.Errorf("failed to back up %s before migrating it to version %d: %v", location, currentVersion(), err)
+	}
+
+	return writeDocument(location, doc, indent)
+}
@@ -0,0 +1,149 @@
+//go:build unit
+
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// baselineConfig is ~/.kz.yml as written by previous releases, before the version key existed
+const baselineConfig = `contexts:
+    - context-1
+    - context-2
+namespaces:
+    - ns1
+`
+
+func TestMigrate(t *testing.T) {
+	t.Run("load baseline file as version 1 without rewriting it", func(t *testing.T) {
+		home := setupHome(t)
+		location := filepath.Join(home, ".config", "kz", "config.yml")
+		require.NoError(t, os.MkdirAll(filepath.Dir(location), 0755))
+		require.NoError(t, os.WriteFile(location, []byte(baselineConfig), 0644))
+
+		c, err := LoadFromDefaultLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, 1, c.Version)
+		require.Equal(t, []string{"context-1", "context-2"}, c.Contexts)
+		require.Equal(t, []string{"ns1"}, c.Namespaces)
+		require.Equal(t, baselineConfig, readConfig(t, location))
+		require.NoFileExists(t, location+".v1.bak")
+	})
+
+	t.Run("migrate baseline legacy file into config file of current version", func(t *testing.T) {
+		home := setupHome(t)
+		legacy := filepath.Join(home, ".kz.yml")
+		require.NoError(t, os.WriteFile(legacy, []byte(baselineConfig), 0644))
+
+		c, err := LoadFromDefaultLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"context-1", "context-2"}, c.Contexts)
+		require.Equal(t, []string{"ns1"}, c.Namespaces)
+		require.Equal(t, "version: 1\n"+baselineConfig, readConfig(t, filepath.Join(home, ".config", "kz", "config.yml")))
+	})
+
+	t.Run("migrate file of older version in memory when loaded directly", func(t *testing.T) {
+		registerMigration(t)
+		location := filepath.Join(t.TempDir(), "config.yml")
+		require.NoError(t, os.WriteFile(location, []byte(baselineConfig), 0644))
+
+		c, err := Load(location)
+
+		require.NoError(t, err)
+		require.Equal(t, 2, c.Version)
+		require.Equal(t, []string{"*prod*"}, c.Protected)
+		require.Equal(t, baselineConfig, readConfig(t, location))
+		require.NoFileExists(t, location+".v1.bak")
+	})
+
+	t.Run("rewrite user config file of older version with backup", func(t *testing.T) {
+		registerMigration(t)
+		home := setupHome(t)
+		location := filepath.Join(home, ".config", "kz", "config.yml")
+		require.NoError(t, os.MkdirAll(filepath.Dir(location), 0755))
+		require.NoError(t, os.WriteFile(location, []byte("# tracked by kz\n"+baselineConfig), 0644))
+
+		c, err := LoadFromDefaultLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"*prod*"}, c.Protected)
+		require.Equal(t, "# tracked by kz\n"+baselineConfig, readConfig(t, location+".v1.bak"))
+		require.Equal(t, `version: 2
+# tracked by kz
+contexts:
+    - context-1
+    - context-2
+namespaces:
+    - ns1
+protected:
+    - '*prod*'
+`, readConfig(t, location))
+	})
+
+	t.Run("migrate other layers in memory only", func(t *testing.T) {
+		registerMigration(t)
+		home := setupHome(t)
+		system := filepath.Join(home, "system.yml")
+		require.NoError(t, os.WriteFile(system, []byte(baselineConfig), 0644))
+
+		c, err := LoadFromDefaultLocation()
+
+		require.NoError(t, err)
+		require.Equal(t, []string{"*prod*"}, c.Protected)
+		require.Equal(t, baselineConfig, readConfig(t, system))
+		require.NoFileExists(t, system+".v1.bak")
+	})
+
+	t.Run("leave file of current version untouched", func(t *testing.T) {
+		registerMigration(t)
+		home := setupHome(t)
+		location := filepath.Join(home, ".config", "kz", "config.yml")
+		require.NoError(t, Save(location, &Config{Namespaces: []string{"ns1"}}))
+
+		_, err := LoadFromDefaultLocation()
+
+		require.NoError(t, err)
+		require.NoFileExists(t, location+".v2.bak")
+	})
+
+	t.Run("return error when file is newer than supported version", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "config.yml")
+		require.NoError(t, os.WriteFile(location, []byte("version: 99\n"), 0644))
+
+		_, err := Load(location)
+
+		require.ErrorContains(t, err, "has version 99 which is newer than version 1 supported by this kz")
+	})
+
+	t.Run("return error when version is not a number", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "config.yml")
+		require.NoError(t, os.WriteFile(location, []byte("contexts: []\nversion: two\n"), 0644))
+
+		_, err := Load(location)
+
+		require.ErrorContains(t, err, "invalid version 'two'")
+		require.ErrorContains(t, err, "at line 2")
+	})
+}
+
+// registerMigration registers a migration to version 2 protecting production contexts for the duration of the test,
+// since no shape change has needed a migration yet
+func registerMigration(t *testing.T) {
+	original := migrations
+	t.Cleanup(func() { migrations = original })
+
+	migrations = append(migrations, migration{
+		description: "protect production contexts",
+		migrate: func(root *yaml.Node) {
+			root.Content = append(root.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "protected"},
+				&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: "*prod*"}}})
+		},
+	})
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ProjectFileName is the name of project configuration files, discovered by walking up from the working directory
+const ProjectFileName = ".kz.yml"
+
+// Project declares the context and namespace a directory tree is meant to be used with
+type Project struct {
+	// Location is the path of the project configuration file
+	Location  string `yaml:"-"`
+	Context   string `yaml:"context"`
+	Namespace string `yaml:"namespace"`
+	// AllowedContexts contains glob patterns of contexts that can be used in the project
+	AllowedContexts []string `yaml:"allowedContexts"`
+}
+
+// IsAllowed reports whether the context can be used in the project, all contexts are allowed when no patterns are declared
+func (p *Project) IsAllowed(context string) bool {
+	if len(p.AllowedContexts) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(p.AllowedContexts, func(pattern string) bool {
+		return MatchGlob(pattern, context)
+	})
+}
+
+// FindProject returns the project configuration in dir or its closest parent, and false when there's none.
+// Files at the ignored locations, .e.g. the user configuration file in home directory, are skipped
+func FindProject(dir string, ignored ...string) (*Project, bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve directory %s: %v", dir, err)
+	}
+
+	for {
+		location := filepath.Join(dir, ProjectFileName)
+		if !slices.Contains(ignored, location) {
+			p, err := LoadProject(location)
+			if err == nil {
+				return p, true, nil
+			}
+
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, false, err
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, false, nil
+		}
+		dir = parent
+	}
+}
+
+func LoadProject(location string) (*Project, error) {
+	content, err := os.ReadFile(location)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("failed to load project config from location %s: %v", location, err)
+	}
+
+	p := Project{Location: location}
+	if err := yaml.Unmarshal(content, &p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal project config from location %s: %v", location, err)
+	}
+
+	return &p, nil
+}
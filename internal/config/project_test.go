@@ -0,0 +1,64 @@
+//go:build unit
+
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindProject(t *testing.T) {
+	t.Run("return project config from closest parent directory", func(t *testing.T) {
+		root := t.TempDir()
+		nested := filepath.Join(root, "service", "deploy")
+		require.NoError(t, os.MkdirAll(nested, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, ProjectFileName), []byte(`context: prod
+namespace: payments
+allowedContexts:
+  - "*prod*"
+`), 0644))
+
+		p, found, err := FindProject(nested)
+
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, &Project{
+			Location:        filepath.Join(root, ProjectFileName),
+			Context:         "prod",
+			Namespace:       "payments",
+			AllowedContexts: []string{"*prod*"},
+		}, p)
+	})
+
+	t.Run("skip ignored locations", func(t *testing.T) {
+		root := t.TempDir()
+		nested := filepath.Join(root, "home")
+		require.NoError(t, os.MkdirAll(nested, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, ProjectFileName), []byte("context: outer\n"), 0644))
+		ignored := filepath.Join(nested, ProjectFileName)
+		require.NoError(t, os.WriteFile(ignored, []byte("context: ignored\n"), 0644))
+
+		p, found, err := FindProject(nested, ignored)
+
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, "outer", p.Context)
+	})
+}
+
+func TestProjectIsAllowed(t *testing.T) {
+	t.Run("allow all contexts when no patterns are declared", func(t *testing.T) {
+		p := Project{}
+
+		require.True(t, p.IsAllowed("staging"))
+	})
+
+	t.Run("allow only contexts matching declared patterns", func(t *testing.T) {
+		p := Project{AllowedContexts: []string{"*prod*"}}
+
+		require.True(t, p.IsAllowed("eu-prod"))
+		require.False(t, p.IsAllowed("staging"))
+	})
+}
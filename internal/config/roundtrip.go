@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+)
+
+// Update applies update to configuration at location without state and writes back only the values that changed,
+// so that comments, ordering and keys unknown to kz survive. Unlike Save, the file isn't re-marshalled from Config
+func Update(location string, update func(c *Config) error) error {
+	doc, indent, err := loadDocument(location, true)
+	if err != nil {
+		return err
+	}
+
+	if doc == nil {
+		doc = &yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	root := doc.Content[0]
+	var c Config
+	if err := root.Decode(&c); err != nil {
+		return fmt.Errorf("failed to unmarshal yaml config from location %s: %v", location, err)
+	}
+
+	if err := update(&c); err != nil {
+		return err
+	}
+
+	c.Version = currentVersion()
+	var updated yaml.Node
+	if err := updated.Encode(&c); err != nil {
+		return fmt.Errorf("failed to marshal config to yaml: %v", err)
+	}
+
+	doc.Content[0] = syncNode(root, &updated, func(key string) bool {
+		return !isConfigKey(key)
+	})
+
+	return writeDocument(location, doc, indent)
+}
+
+// defaultIndent is the indentation of files written by yaml.Marshal, used for files that don't have nested values yet
+const defaultIndent = 4
+
+// writeDocument writes the document node with the given indentation
+func writeDocument(location string, doc *yaml.Node, indent int) error {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(indent)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to marshal config to yaml: %v", err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to marshal config to yaml: %v", err)
+	}
+
+	return writeFile(location, buffer.Bytes())
+}
+
+// indentOf returns indentation of the first indented line of yaml content, ignoring comments
+func indentOf(content []byte) int {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(trimmed) == 0 || len(trimmed) == len(line) || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		return len(line) - len(trimmed)
+	}
+
+	return defaultIndent
+}
+
+// syncNode changes original to hold the value of updated while reusing nodes of original, and with them their comments and styles,
+// wherever values haven't changed. Keys of mappings missing from updated are removed unless keep reports that they should be kept
+func syncNode(original *yaml.Node, updated *yaml.Node, keep func(key string) bool) *yaml.Node {
+	if sameValue(original, updated) {
+		return original
+	}
+
+	if original.Kind != updated.Kind {
+		return withComments(updated, original)
+	}
+
+	switch updated.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(original.Content); i += 2 {
+			key, value := original.Content[i], original.Content[i+1]
+			if index := mappingIndex(updated, key.Value); index >= 0 {
+				content = append(content, key, syncNode(value, updated.Content[index+1], nil))
+			} else if keep != nil && keep(key.Value) {
+				content = append(content, key, value)
+			}
+		}
+
+		// keys added by update are appended, empty values are left out like they would be by omitempty
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			if mappingIndex(original, updated.Content[i].Value) < 0 && !isEmptyNode(updated.Content[i+1]) {
+				content = append(content, updated.Content[i], updated.Content[i+1])
+			}
+		}
+
+		original.Content = content
+		return original
+	case yaml.SequenceNode:
+		unused := append([]*yaml.Node{}, original.Content...)
+		var content []*yaml.Node
+		for _, item := range updated.Content {
+			index := -1
+			for i, candidate := range unused {
+				if sameValue(candidate, item) {
+					index = i
+					break
+				}
+			}
+
+			if index >= 0 {
+				content = append(content, unused[index])
+				unused = append(unused[:index], unused[index+1:]...)
+			} else {
+				content = append(content, item)
+			}
+		}
+
+		original.Content = content
+		return original
+	default:
+		return withComments(updated, original)
+	}
+}
+
+// withComments moves comments of the replaced node to its replacement
+func withComments(replacement *yaml.Node, replaced *yaml.Node) *yaml.Node {
+	replacement.HeadComment = replaced.HeadComment
+	replacement.LineComment = replaced.LineComment
+	replacement.FootComment = replaced.FootComment
+	return replacement
+}
+
+// sameValue reports whether both nodes decode to the same value, empty values are considered the same regardless of their type
+func sameValue(left *yaml.Node, right *yaml.Node) bool {
+	var l, r any
+	if err := left.Decode(&l); err != nil {
+		return false
+	}
+
+	if err := right.Decode(&r); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(l, r) || (isEmpty(l) && isEmpty(r))
+}
+
+func isEmptyNode(node *yaml.Node) bool {
+	var v any
+	return node.Decode(&v) == nil && isEmpty(v)
+}
+
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	default:
+		return false
+	}
+}
+
+// isConfigKey reports whether the key is a top-level key of Config
+func isConfigKey(key string) bool {
+	_, ok := fieldByKey(reflect.TypeOf(Config{}), key)
+	return ok
+}
@@ -0,0 +1,139 @@
+//go:build unit
+
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const commentedConfig = `version: 1
+# contexts synced from kubeconfig
+contexts:
+  - context1
+namespaces:
+  - ns1 # payments team
+  # shared by all teams
+  - ns2
+custom: kept by kz
+protected:
+  - "*prod*"
+`
+
+func TestUpdate(t *testing.T) {
+	t.Run("keep comments, ordering and unknown keys when adding namespaces", func(t *testing.T) {
+		location := writeConfig(t, commentedConfig)
+
+		err := Update(location, func(c *Config) error {
+			c.AddNamespaces("ns3")
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, `version: 1
+# contexts synced from kubeconfig
+contexts:
+  - context1
+namespaces:
+  - ns1 # payments team
+  # shared by all teams
+  - ns2
+  - ns3
+custom: kept by kz
+protected:
+  - "*prod*"
+`, readConfig(t, location))
+	})
+
+	t.Run("keep comments of remaining namespaces when deleting namespaces", func(t *testing.T) {
+		location := writeConfig(t, commentedConfig)
+
+		err := Update(location, func(c *Config) error {
+			c.DeleteNamespaces("ns1")
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, `version: 1
+# contexts synced from kubeconfig
+contexts:
+  - context1
+namespaces:
+  # shared by all teams
+  - ns2
+custom: kept by kz
+protected:
+  - "*prod*"
+`, readConfig(t, location))
+	})
+
+	t.Run("keep comments when syncing contexts", func(t *testing.T) {
+		location := writeConfig(t, commentedConfig)
+
+		err := Update(location, func(c *Config) error {
+			c.Contexts = []string{"context0", "context1"}
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Contains(t, readConfig(t, location), `# contexts synced from kubeconfig
+contexts:
+  - context0
+  - context1
+`)
+	})
+
+	t.Run("remove keys emptied by update", func(t *testing.T) {
+		location := writeConfig(t, commentedConfig)
+
+		err := Update(location, func(c *Config) error {
+			c.Protected = nil
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.NotContains(t, readConfig(t, location), "protected")
+		require.Contains(t, readConfig(t, location), "custom: kept by kz")
+	})
+
+	t.Run("create file when it doesn't exist", func(t *testing.T) {
+		location := filepath.Join(t.TempDir(), "config.yml")
+
+		err := Update(location, func(c *Config) error {
+			c.AddNamespaces("ns1")
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, `version: 1
+namespaces:
+    - ns1
+`, readConfig(t, location))
+	})
+
+	t.Run("return error from update without writing", func(t *testing.T) {
+		location := writeConfig(t, commentedConfig)
+
+		err := Update(location, func(c *Config) error {
+			c.AddNamespaces("ns3")
+			return os.ErrInvalid
+		})
+
+		require.ErrorIs(t, err, os.ErrInvalid)
+		require.Equal(t, commentedConfig, readConfig(t, location))
+	})
+}
+
+func writeConfig(t *testing.T, content string) string {
+	location := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(location, []byte(content), 0644))
+	return location
+}
+
+func readConfig(t *testing.T, location string) string {
+	content, err := os.ReadFile(location)
+	require.NoError(t, err)
+	return string(content)
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Problem is an issue found in a configuration file. Line is 0 when the problem can't be tied to a line.
+// Warnings are problems that don't prevent configuration from being used, e.g. references to contexts that are not in kubeconfig anymore
+type Problem struct {
+	Location string
+	Line     int
+	Message  string
+	Warning  bool
+}
+
+func (p Problem) String() string {
+	message := p.Message
+	if p.Warning {
+		message = "warning: " + message
+	}
+
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.Location, message)
+	}
+
+	return fmt.Sprintf("%s:%d: %s", p.Location, p.Line, message)
+}
+
+// paths of values holding glob patterns of context names, `[]` stands for every item of a sequence and `*` for every value of a mapping
+var patternPaths = [][]string{
+	{"protected", "[]"},
+	{"tags", "*", "[]"},
+	{"hooks", "[]", "match"},
+	{"env", "[]", "match"},
+	{"prompt", "contexts", "[]", "match"},
+}
+
+// paths of values referencing tags
+var tagPaths = [][]string{
+	{"hooks", "[]", "tag"},
+	{"env", "[]", "tag"},
+}
+
+// ValidateLayers checks configuration layers for unknown keys, values of wrong types, invalid glob patterns,
+// undefined tags and references to contexts that are not in contexts, which are contexts available in kubeconfig.
+// Tags can be defined in any layer. Problems are ordered by layer and line
+func ValidateLayers(layers []Layer, contexts []string) ([]Problem, error) {
+	nodes := make([]*yaml.Node, len(layers))
+	tags := make(map[string]bool)
+	for i, l := range layers {
+		node, err := loadNode(l.Location, false)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes[i] = node
+		if node != nil {
+			for _, key := range keysAt(node, "tags") {
+				tags[key] = true
+			}
+		}
+	}
+
+	var problems []Problem
+	for i, l := range layers {
+		if nodes[i] != nil {
+			problems = append(problems, validateNode(l.Location, nodes[i], tags, contexts)...)
+		}
+	}
+
+	return problems, nil
+}
+
+func validateNode(location string, root *yaml.Node, tags map[string]bool, contexts []string) []Problem {
+	problem := func(node *yaml.Node, format string, args ...any) Problem {
+		return Problem{Location: location, Line: node.Line, Message: fmt.Sprintf(format, args...)}
+	}
+	warning := func(node *yaml.Node, format string, args ...any) Problem {
+		p := problem(node, format, args...)
+		p.Warning = true
+		return p
+	}
+
+	problems := validateKeys(location, root, reflect.TypeOf(Config{}), "")
+	problems = append(problems, validateTypes(location, root)...)
+
+	for _, path := range patternPaths {
+		for _, n := range nodesAt(root, path) {
+			if _, err := CompileGlob(n.Value); err != nil {
+				problems = append(problems, problem(n, "%v", err))
+			} else if !strings.ContainsAny(n.Value, "*?[") && !slices.Contains(contexts, n.Value) {
+				problems = append(problems, warning(n, "context '%s' doesn't exist in kubeconfig", n.Value))
+			}
+		}
+	}
+
+	for _, path := range tagPaths {
+		for _, n := range nodesAt(root, path) {
+			if len(n.Value) > 0 && !tags[n.Value] {
+				problems = append(problems, problem(n, "tag '%s' is not defined", n.Value))
+			}
+		}
+	}
+
+	for _, n := range nodesAt(root, []string{"contexts", "[]"}) {
+		if !slices.Contains(contexts, n.Value) {
+			problems = append(problems, warning(n, "context '%s' doesn't exist in kubeconfig, run `kz ctx sync` to update tracked contexts", n.Value))
+		}
+	}
+
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		return a.Line - b.Line
+	})
+	return problems
+}
+
+// validateKeys reports keys of mappings that don't correspond to fields of the struct type the mapping is decoded into
+func validateKeys(location string, node *yaml.Node, t reflect.Type, path string) []Problem {
+	var problems []Problem
+	switch {
+	case t == reflect.TypeOf(time.Time{}) || t == reflect.TypeOf(time.Duration(0)):
+		return nil
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fieldByKey(t, key.Value)
+			if !ok {
+				problems = append(problems, Problem{Location: location, Line: key.Line, Message: fmt.Sprintf("unknown key '%s'", path+key.Value)})
+				continue
+			}
+
+			problems = append(problems, validateKeys(location, value, field.Type, path+key.Value+".")...)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			problems = append(problems, validateKeys(location, item, t.Elem(), fmt.Sprintf("%s%d.", path, i))...)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			problems = append(problems, validateKeys(location, node.Content[i+1], t.Elem(), path+node.Content[i].Value+".")...)
+		}
+	}
+
+	return problems
+}
+
+// validateTypes reports values that can't be decoded into types of their fields
+func validateTypes(location string, root *yaml.Node) []Problem {
+	var c Config
+	err := root.Decode(&c)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return []Problem{{Location: location, Message: err.Error()}}
+	}
+
+	var problems []Problem
+	for _, e := range typeErr.Errors {
+		problems = append(problems, problemFromTypeError(location, e))
+	}
+	return problems
+}
+
+// problemFromTypeError extracts the line from yaml errors formatted like `line 3: cannot unmarshal ...`
+func problemFromTypeError(location string, e string) Problem {
+	if rest, ok := strings.CutPrefix(e, "line "); ok {
+		if number, message, ok := strings.Cut(rest, ": "); ok {
+			if line, err := strconv.Atoi(number); err == nil {
+				return Problem{Location: location, Line: line, Message: message}
+			}
+		}
+	}
+
+	return Problem{Location: location, Message: e}
+}
+
+// fieldByKey returns the struct field the yaml key is decoded into
+func fieldByKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == key && name != "-" {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// nodesAt returns scalar nodes at the path, see patternPaths for the syntax
+func nodesAt(node *yaml.Node, path []string) []*yaml.Node {
+	if len(path) == 0 {
+		if node.Kind == yaml.ScalarNode {
+			return []*yaml.Node{node}
+		}
+		return nil
+	}
+
+	var children []*yaml.Node
+	switch {
+	case path[0] == "[]" && node.Kind == yaml.SequenceNode:
+		children = node.Content
+	case path[0] == "*" && node.Kind == yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			children = append(children, node.Content[i])
+		}
+	case node.Kind == yaml.MappingNode:
+		if index := mappingIndex(node, path[0]); index >= 0 {
+			children = append(children, node.Content[index+1])
+		}
+	}
+
+	var nodes []*yaml.Node
+	for _, child := range children {
+		nodes = append(nodes, nodesAt(child, path[1:])...)
+	}
+	return nodes
+}
+
+// keysAt returns keys of the mapping under the key of the top-level mapping
+func keysAt(root *yaml.Node, key string) []string {
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	index := mappingIndex(root, key)
+	if index < 0 || root.Content[index+1].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var keys []string
+	for i := 0; i < len(root.Content[index+1].Content); i += 2 {
+		keys = append(keys, root.Content[index+1].Content[i].Value)
+	}
+	return keys
+}
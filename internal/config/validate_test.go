@@ -0,0 +1,91 @@
+//go:build unit
+
+package config
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateLayers(t *testing.T) {
+	t.Run("return no problems for valid configuration", func(t *testing.T) {
+		location := writeConfig(t, `version: 1
+contexts:
+  - dev
+protected:
+  - "*prod*"
+tags:
+  eks:
+    - "arn:aws:eks:*"
+hooks:
+  - tag: eks
+    pre: aws sso login
+`)
+
+		problems, err := ValidateLayers([]Layer{{Name: UserLayer, Location: location}}, []string{"dev"})
+
+		require.NoError(t, err)
+		require.Empty(t, problems)
+	})
+
+	t.Run("report problems with line numbers", func(t *testing.T) {
+		location := writeConfig(t, `contexts:
+  - deleted
+protectd:
+  - "*prod*"
+protectedMaxDuration: soon
+hooks:
+  - match: "[prod"
+    tag: gke
+    command: echo
+prompt:
+  contexts:
+    - match: dev
+      colour: green
+`)
+
+		problems, err := ValidateLayers([]Layer{{Name: UserLayer, Location: location}}, []string{"dev"})
+
+		require.NoError(t, err)
+		var messages []string
+		for _, p := range problems {
+			messages = append(messages, p.String())
+		}
+		require.Equal(t, []string{
+			location + ":2: warning: context 'deleted' doesn't exist in kubeconfig, run `kz ctx sync` to update tracked contexts",
+			location + ":3: unknown key 'protectd'",
+			location + ":5: cannot unmarshal !!str `soon` into time.Duration",
+			location + ":7: invalid glob pattern '[prod': unclosed character class",
+			location + ":8: tag 'gke' is not defined",
+			location + ":9: unknown key 'hooks.0.command'",
+			location + ":13: unknown key 'prompt.contexts.0.colour'",
+		}, messages)
+	})
+
+	t.Run("resolve tags defined in other layers", func(t *testing.T) {
+		team := writeConfig(t, `tags:
+  eks:
+    - "arn:aws:eks:*"
+`)
+		user := writeConfig(t, `env:
+  - tag: eks
+    vars:
+      AWS_PROFILE: default
+`)
+
+		problems, err := ValidateLayers([]Layer{{Name: TeamLayer, Location: team}, {Name: UserLayer, Location: user}}, nil)
+
+		require.NoError(t, err)
+		require.Empty(t, problems)
+	})
+
+	t.Run("validate configuration of previous versions after migrating it", func(t *testing.T) {
+		registerMigration(t)
+		location := writeConfig(t, "namespaces:\n    - ns1\n")
+
+		problems, err := ValidateLayers([]Layer{{Name: UserLayer, Location: location}}, nil)
+
+		require.NoError(t, err)
+		require.Empty(t, problems)
+	})
+}
@@ -0,0 +1,291 @@
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat formats backup IDs so that they sort chronologically
+const backupTimeFormat = "20060102-150405.000000"
+
+// BackupPolicy controls backups of kubeconfig files taken whenever kz modifies them.
+// Backups of a file are kept in Directory under the absolute path of the file, e.g. <Directory>/home/user/.kube/config/<ID>
+type BackupPolicy struct {
+	Directory string
+	// Keep is the number of backups kept for each kubeconfig file, zero disables backups
+	Keep int
+}
+
+// Backup is a copy of a kubeconfig file taken before kz modified it.
+// Files modified by the same change share the same ID
+type Backup struct {
+	ID        string
+	File      string
+	Location  string
+	CreatedAt time.Time
+}
+
+var backupPolicy BackupPolicy
+
+// SetBackupPolicy sets how kubeconfig files are backed up by functions modifying them, backups are disabled until it's set
+func SetBackupPolicy(policy BackupPolicy) {
+	backupPolicy = policy
+}
+
+// HasBackupPolicy reports whether SetBackupPolicy has been called with a backup directory
+func HasBackupPolicy() bool {
+	return len(backupPolicy.Directory) > 0
+}
+
+// snapshot keeps content of kubeconfig files before they're modified, so that backups are only taken of files that actually changed
+type snapshot map[string][]byte
+
+func takeSnapshot(files []string) (snapshot, error) {
+	s := make(snapshot)
+	if backupPolicy.Keep <= 0 {
+		return s, nil
+	}
+
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to read kube config %s before modifying it: %v", f, err)
+		}
+		s[f] = content
+	}
+
+	return s, nil
+}
+
+// backUpChanged stores previous content of files that have been changed since the snapshot was taken, then removes backups exceeding the policy
+func (s snapshot) backUpChanged(at time.Time) error {
+	id := at.UTC().Format(backupTimeFormat)
+	for f, previous := range s {
+		current, err := os.ReadFile(f)
+		if err == nil && bytes.Equal(current, previous) {
+			continue
+		}
+
+		if err := writeBackup(f, id, previous); err != nil {
+			return err
+		}
+
+		if err := pruneBackups(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeBackup(file string, id string, content []byte) error {
+	dir, err := backupDirectory(file)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create backup directory %s: %v", dir, err)
+	}
+
+	// backups contain credentials, so they're only readable by the owner like kubeconfig files
+	location := filepath.Join(dir, id)
+	if err := os.WriteFile(location, content, 0600); err != nil {
+		return fmt.Errorf("failed to back up kube config %s to %s: %v", file, location, err)
+	}
+
+	return nil
+}
+
+func pruneBackups(file string) error {
+	backups, err := backupsOf(file)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > backupPolicy.Keep {
+		if err := os.Remove(backups[0].Location); err != nil {
+			return fmt.Errorf("failed to remove old backup %s: %v", backups[0].Location, err)
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// Backups returns backups of all kubeconfig files, oldest first
+func Backups() ([]Backup, error) {
+	if len(backupPolicy.Directory) == 0 {
+		return nil, nil
+	}
+
+	var backups []Backup
+	err := filepath.WalkDir(backupPolicy.Directory, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		createdAt, err := time.Parse(backupTimeFormat, d.Name())
+		if err != nil {
+			// not a backup
+			return nil
+		}
+
+		relative, err := filepath.Rel(backupPolicy.Directory, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+
+		backups = append(backups, Backup{
+			ID:        d.Name(),
+			File:      string(filepath.Separator) + relative,
+			Location:  path,
+			CreatedAt: createdAt,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kube config backups in %s: %v", backupPolicy.Directory, err)
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].ID < backups[j].ID
+	})
+	return backups, nil
+}
+
+func backupsOf(file string) ([]Backup, error) {
+	backups, err := Backups()
+	if err != nil {
+		return nil, err
+	}
+
+	absolute, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(backups, func(b Backup) bool {
+		return b.File != absolute
+	}), nil
+}
+
+// RestoreBackup restores all files backed up with the given ID, or with the latest ID when id is empty, and returns the restored backups.
+// Current content of restored files is backed up first so that restoring can be undone
+func RestoreBackup(id string) ([]Backup, error) {
+	backups, err := Backups()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(backups) == 0 {
+		return nil, errors.New("no kube config backups available")
+	}
+
+	if len(id) == 0 {
+		id = backups[len(backups)-1].ID
+	}
+
+	restored := slices.DeleteFunc(backups, func(b Backup) bool {
+		return b.ID != id
+	})
+	if len(restored) == 0 {
+		return nil, fmt.Errorf("kube config backup with ID %s does not exist", id)
+	}
+
+	var files []string
+	for _, b := range restored {
+		files = append(files, b.File)
+	}
+
+	s, err := takeSnapshot(files)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range restored {
+		content, err := os.ReadFile(b.Location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kube config backup %s: %v", b.Location, err)
+		}
+
+		if err := replaceFile(b.File, content); err != nil {
+			return nil, fmt.Errorf("failed to restore kube config %s from backup %s: %v", b.File, b.ID, err)
+		}
+	}
+
+	if err := s.backUpChanged(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// replaceFile writes content to a temporary file next to the file then renames it over the file, so that the file is never partially written.
+// Symlinks are followed and permissions of the file are kept, new files are only readable by the owner like kubeconfig files
+func replaceFile(location string, content []byte) error {
+	mode := os.FileMode(0600)
+	if resolved, err := filepath.EvalSymlinks(location); err == nil {
+		location = resolved
+		if info, err := os.Stat(location); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(location), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(location), "."+filepath.Base(location)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), location)
+}
+
+func backupDirectory(file string) (string, error) {
+	absolute, err := filepath.Abs(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine absolute path of kube config %s: %v", file, err)
+	}
+
+	return filepath.Join(backupPolicy.Directory, strings.TrimPrefix(absolute, string(filepath.Separator))), nil
+}
@@ -0,0 +1,149 @@
+//go:build unit
+
+package kube
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackups(t *testing.T) {
+	t.Run("back up kubeconfig file before modifying it", func(t *testing.T) {
+		configPath := setupBackups(t, 10)
+		original, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+
+		require.NoError(t, SwitchContextTo("context-1"))
+
+		backups, err := Backups()
+		require.NoError(t, err)
+		require.Len(t, backups, 1)
+		require.Equal(t, configPath, backups[0].File)
+		content, err := os.ReadFile(backups[0].Location)
+		require.NoError(t, err)
+		require.Equal(t, original, content)
+	})
+
+	t.Run("keep configured number of backups", func(t *testing.T) {
+		setupBackups(t, 2)
+
+		require.NoError(t, SwitchContextTo("context-1"))
+		require.NoError(t, SwitchContextTo("context-2"))
+		require.NoError(t, SwitchContextTo("context-1"))
+
+		backups, err := Backups()
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+	})
+
+	t.Run("not back up files that haven't changed", func(t *testing.T) {
+		setupBackups(t, 10)
+
+		require.NoError(t, SwitchContextTo("context-2"))
+
+		backups, err := Backups()
+		require.NoError(t, err)
+		require.Empty(t, backups)
+	})
+
+	t.Run("not back up when backups are disabled", func(t *testing.T) {
+		setupBackups(t, 0)
+
+		require.NoError(t, SwitchContextTo("context-1"))
+
+		backups, err := Backups()
+		require.NoError(t, err)
+		require.Empty(t, backups)
+	})
+}
+
+func TestRestoreBackup(t *testing.T) {
+	t.Run("restore latest backup and back up current content first", func(t *testing.T) {
+		configPath := setupBackups(t, 10)
+		require.NoError(t, SwitchContextTo("context-1"))
+
+		restored, err := RestoreBackup("")
+
+		require.NoError(t, err)
+		require.Len(t, restored, 1)
+		currentContext, err := CurrentContext()
+		require.NoError(t, err)
+		require.Equal(t, "context-2", currentContext)
+
+		backups, err := Backups()
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+		content, err := os.ReadFile(backups[1].Location)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "current-context: context-1")
+		require.Equal(t, configPath, backups[1].File)
+	})
+
+	t.Run("restore backup by ID", func(t *testing.T) {
+		setupBackups(t, 10)
+		require.NoError(t, SwitchContextTo("context-1"))
+		require.NoError(t, SwitchNamespaceTo("ns1"))
+		backups, err := Backups()
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+
+		_, err = RestoreBackup(backups[0].ID)
+
+		require.NoError(t, err)
+		currentContext, err := CurrentContext()
+		require.NoError(t, err)
+		require.Equal(t, "context-2", currentContext)
+	})
+
+	t.Run("replace file through symlink keeping its permissions", func(t *testing.T) {
+		configPath := setupBackups(t, 10)
+		require.NoError(t, os.Chmod(configPath, 0640))
+		link := filepath.Join(t.TempDir(), "config")
+		require.NoError(t, os.Symlink(configPath, link))
+		t.Setenv("KUBECONFIG", link)
+		require.NoError(t, SwitchContextTo("context-1"))
+
+		_, err := RestoreBackup("")
+
+		require.NoError(t, err)
+		info, err := os.Lstat(link)
+		require.NoError(t, err)
+		require.Equal(t, os.ModeSymlink, info.Mode().Type())
+		info, err = os.Stat(configPath)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+		currentContext, err := CurrentContext()
+		require.NoError(t, err)
+		require.Equal(t, "context-2", currentContext)
+	})
+
+	t.Run("return error when backup doesn't exist", func(t *testing.T) {
+		setupBackups(t, 10)
+		require.NoError(t, SwitchContextTo("context-1"))
+
+		_, err := RestoreBackup("20000101-000000.000000")
+
+		require.ErrorContains(t, err, "kube config backup with ID 20000101-000000.000000 does not exist")
+	})
+
+	t.Run("return error when there are no backups", func(t *testing.T) {
+		setupBackups(t, 10)
+
+		_, err := RestoreBackup("")
+
+		require.ErrorContains(t, err, "no kube config backups available")
+	})
+}
+
+func setupBackups(t *testing.T, keep int) string {
+	configPath := copyFileToTmp(t, "testdata/kubeconfig-3")
+	t.Cleanup(func() { os.Remove(configPath) })
+	t.Setenv("KUBECONFIG", configPath)
+	t.Setenv(SessionEnvVar, "")
+
+	SetBackupPolicy(BackupPolicy{Directory: t.TempDir(), Keep: keep})
+	t.Cleanup(func() { SetBackupPolicy(BackupPolicy{}) })
+	return configPath
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
+	"time"
 )
 
 func ContextsFromConfig() ([]string, error) {
@@ -22,6 +23,36 @@ func ContextsFromConfig() ([]string, error) {
 	return contexts, nil
 }
 
+func CurrentContext() (string, error) {
+	ca := clientcmd.NewDefaultPathOptions()
+	cfg, err := ca.GetStartingConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to get starting config: %v", err)
+	}
+
+	return cfg.CurrentContext, nil
+}
+
+// NamespaceOf returns namespace of the given context, or the default namespace when the context doesn't set one
+func NamespaceOf(ctx string) (string, error) {
+	ca := clientcmd.NewDefaultPathOptions()
+	cfg, err := ca.GetStartingConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to get starting config: %v", err)
+	}
+
+	c, err := contextOf(cfg, ca.GetLoadingPrecedence(), ctx, false)
+	if err != nil {
+		return "", err
+	}
+
+	if len(c.Namespace) == 0 {
+		return defaultNamespace, nil
+	}
+
+	return c.Namespace, nil
+}
+
 func SwitchContextTo(ctx string) error {
 	if len(ctx) == 0 {
 		return errors.New("context to switch to is required")
@@ -33,17 +64,18 @@ func SwitchContextTo(ctx string) error {
 		return fmt.Errorf("failed to get starting config: %v", err)
 	}
 
-	if !contextExists(cfg.Contexts, ctx) {
-		return fmt.Errorf("context with name %s does not exist in kube config file(s)", ctx)
+	c, err := contextOf(cfg, ca.GetLoadingPrecedence(), ctx, false)
+	if err != nil {
+		return err
+	}
+
+	if err := checkReferences(cfg, ctx, c); err != nil {
+		return err
 	}
 
 	cfg.CurrentContext = ctx
 
-	if err := clientcmd.ModifyConfig(ca, *cfg, true); err != nil {
-		return fmt.Errorf("failed to modify config: %v", err)
-	}
-
-	return nil
+	return writeConfig(ca, cfg)
 }
 
 func SwitchNamespaceTo(namespace string) error {
@@ -61,13 +93,14 @@ func SwitchNamespaceTo(namespace string) error {
 		return fmt.Errorf("unable to switch namespace to %s because current context is not set", namespace)
 	}
 
-	cfg.Contexts[cfg.CurrentContext].Namespace = namespace
-
-	if err := clientcmd.ModifyConfig(ca, *cfg, true); err != nil {
-		return fmt.Errorf("failed to modify config: %v", err)
+	current, err := contextOf(cfg, ca.GetLoadingPrecedence(), cfg.CurrentContext, true)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	current.Namespace = namespace
+
+	return writeConfig(ca, cfg)
 }
 
 func SwitchContextAndNamespace(ctx string, namespace string) error {
@@ -81,26 +114,54 @@ func SwitchContextAndNamespace(ctx string, namespace string) error {
 		return fmt.Errorf("failed to get starting config: %v", err)
 	}
 
-	if !contextExists(cfg.Contexts, ctx) {
-		return fmt.Errorf("context with name %s does not exist in kube config file(s)", ctx)
+	c, err := contextOf(cfg, ca.GetLoadingPrecedence(), ctx, false)
+	if err != nil {
+		return err
+	}
+
+	if err := checkReferences(cfg, ctx, c); err != nil {
+		return err
 	}
 
 	cfg.CurrentContext = ctx
-	cfg.Contexts[ctx].Namespace = namespace
+	c.Namespace = namespace
 
-	if err := clientcmd.ModifyConfig(ca, *cfg, true); err != nil {
-		return fmt.Errorf("failed to modify config: %v", err)
+	return writeConfig(ca, cfg)
+}
+
+// writeConfig writes to the session overlay when a session is active so that the real kubeconfig files are left untouched,
+// then to the state file when one is designated. Otherwise kubeconfig files modified are backed up according to the backup policy
+func writeConfig(ca *clientcmd.PathOptions, cfg *api.Config) error {
+	if overlay, ok := ActiveSession(); ok {
+		return writeOverlay(overlay, cfg)
 	}
 
-	return nil
-}
+	if len(stateFile) > 0 {
+		return writeStateFile(ca, cfg)
+	}
 
-func contextExists(contexts map[string]*api.Context, ctx string) bool {
-	for c := range contexts {
-		if c == ctx {
-			return true
+	return withBackups(ca.GetLoadingPrecedence(), func() error {
+		if err := clientcmd.ModifyConfig(ca, *cfg, true); err != nil {
+			return fmt.Errorf("failed to modify config: %v", err)
 		}
+
+		return nil
+	})
+}
+
+// withBackups backs up files changed by write according to the backup policy,
+// files changed before write fails are backed up too so that they can be restored
+func withBackups(files []string, write func() error) error {
+	s, err := takeSnapshot(files)
+	if err != nil {
+		return err
+	}
+
+	writeErr := write()
+	backupErr := s.backUpChanged(time.Now())
+	if writeErr != nil {
+		return writeErr
 	}
 
-	return false
+	return backupErr
 }
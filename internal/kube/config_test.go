@@ -6,9 +6,7 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/require"
 	"os"
-	"path"
 	"testing"
-	"time"
 )
 
 func TestContextsFromConfig(t *testing.T) {
@@ -28,6 +26,40 @@ func TestContextsFromConfig(t *testing.T) {
 	})
 }
 
+func TestCurrentContext(t *testing.T) {
+	t.Run("return current context from config file", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-3")
+		defer os.Unsetenv("KUBECONFIG")
+
+		currentContext, err := CurrentContext()
+
+		require.NoError(t, err)
+		require.Equal(t, "context-2", currentContext)
+	})
+}
+
+func TestNamespaceOf(t *testing.T) {
+	t.Run("return default namespace when context doesn't set one", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-1")
+		defer os.Unsetenv("KUBECONFIG")
+
+		namespace, err := NamespaceOf("context-1")
+
+		require.NoError(t, err)
+		require.Equal(t, "default", namespace)
+	})
+
+	t.Run("return error when context not exists in config files", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-1")
+		defer os.Unsetenv("KUBECONFIG")
+
+		_, err := NamespaceOf("context-3")
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "context with name context-3 does not exist in kube config file(s)")
+	})
+}
+
 func TestSwitchContextTo(t *testing.T) {
 	t.Run("return error when context to switch is empty", func(t *testing.T) {
 		err := SwitchContextTo("")
@@ -49,6 +81,36 @@ func TestSwitchContextTo(t *testing.T) {
 		require.Contains(t, err.Error(), "context with name context-3 does not exist in kube config file(s)")
 	})
 
+	t.Run("return error when kube config has no contexts", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-empty:testdata/not-existing")
+		defer os.Unsetenv("KUBECONFIG")
+
+		err := SwitchContextTo("context-1")
+
+		var empty *EmptyConfigError
+		require.ErrorAs(t, err, &empty)
+		require.Equal(t, []string{"testdata/kubeconfig-empty"}, empty.Files)
+		require.ErrorContains(t, err, "no contexts defined in kube config file(s) testdata/kubeconfig-empty")
+	})
+
+	t.Run("return error without switching when cluster or user of context to switch to does not exist", func(t *testing.T) {
+		destinationConfigPath := copyFileToTmp(t, "testdata/kubeconfig-4")
+		defer os.Remove(destinationConfigPath)
+
+		os.Setenv("KUBECONFIG", destinationConfigPath)
+		defer os.Unsetenv("KUBECONFIG")
+
+		err := SwitchContextTo("context-without-cluster")
+		require.Equal(t, &ClusterNotFoundError{Context: "context-without-cluster", Cluster: "cluster-2"}, err)
+
+		err = SwitchContextAndNamespace("context-without-user", "ns1")
+		require.Equal(t, &UserNotFoundError{Context: "context-without-user", User: "user-2"}, err)
+
+		currentContext, err := CurrentContext()
+		require.NoError(t, err)
+		require.Equal(t, "deleted-context", currentContext)
+	})
+
 	t.Run("set current context to given context", func(t *testing.T) {
 		destinationConfigPath := copyFileToTmp(t, "testdata/kubeconfig-1")
 		defer os.Remove(destinationConfigPath)
@@ -103,6 +165,21 @@ func TestSwitchNamespaceTo(t *testing.T) {
 		require.Contains(t, err.Error(), "unable to switch namespace to ns2 because current context is not set")
 	})
 
+	t.Run("return error when current context does not exist in config files", func(t *testing.T) {
+		destinationConfigPath := copyFileToTmp(t, "testdata/kubeconfig-4")
+		defer os.Remove(destinationConfigPath)
+
+		os.Setenv("KUBECONFIG", destinationConfigPath)
+		defer os.Unsetenv("KUBECONFIG")
+
+		err := SwitchNamespaceTo("ns2")
+
+		var notFound *ContextNotFoundError
+		require.ErrorAs(t, err, &notFound)
+		require.Equal(t, &ContextNotFoundError{Name: "deleted-context", Current: true}, notFound)
+		require.ErrorContains(t, err, "run `kz ctx` to switch to an existing context")
+	})
+
 	t.Run("set namespace of current context", func(t *testing.T) {
 		destinationConfigPath := copyFileToTmp(t, "testdata/kubeconfig-3")
 		defer os.Remove(destinationConfigPath)
@@ -158,9 +235,12 @@ func TestSwitchContextAndNamespace(t *testing.T) {
 }
 
 func copyFileToTmp(t *testing.T, sourcePath string) string {
-	destinationPath := path.Join(os.TempDir(), fmt.Sprintf("kz-kube-config-%d", time.Now().UnixMilli()))
+	destination, err := os.CreateTemp("", "kz-kube-config-*")
+	require.NoError(t, err)
+	require.NoError(t, destination.Close())
+
 	data, err := os.ReadFile(sourcePath)
 	require.NoError(t, err)
-	require.NoError(t, os.WriteFile(destinationPath, data, 0644))
-	return destinationPath
+	require.NoError(t, os.WriteFile(destination.Name(), data, 0644))
+	return destination.Name()
 }
@@ -0,0 +1,191 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"os"
+)
+
+const defaultNamespace = "default"
+
+// minimalConfig holds only the kubeconfig fields needed to determine current context and namespace
+type minimalConfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// ConfigFiles returns kubeconfig files in loading precedence
+func ConfigFiles() []string {
+	return clientcmd.NewDefaultPathOptions().GetLoadingPrecedence()
+}
+
+// CurrentContextAndNamespace returns current context and its namespace, namespace is `default` when the context doesn't set one.
+// It's meant for latency-sensitive callers like shell prompt so it only parses the fields it needs, following the same merging rules as client-go:
+// the first file that sets current-context wins, and the first file that defines a context wins
+func CurrentContextAndNamespace() (string, string, error) {
+	var configs []minimalConfig
+	for _, f := range ConfigFiles() {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return "", "", fmt.Errorf("failed to read kube config %s: %v", f, err)
+		}
+
+		var c minimalConfig
+		if err := yaml.Unmarshal(content, &c); err != nil {
+			return "", "", fmt.Errorf("failed to parse kube config %s: %v", f, err)
+		}
+		configs = append(configs, c)
+	}
+
+	var currentContext string
+	for _, c := range configs {
+		if len(c.CurrentContext) > 0 {
+			currentContext = c.CurrentContext
+			break
+		}
+	}
+
+	for _, c := range configs {
+		for _, ctx := range c.Contexts {
+			if ctx.Name != currentContext {
+				continue
+			}
+
+			if len(ctx.Context.Namespace) > 0 {
+				return currentContext, ctx.Context.Namespace, nil
+			}
+
+			return currentContext, defaultNamespace, nil
+		}
+	}
+
+	return currentContext, defaultNamespace, nil
+}
+
+// Status describes current context and where it comes from
+type Status struct {
+	Context   string `json:"context" yaml:"context"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Cluster   string `json:"cluster" yaml:"cluster"`
+	Server    string `json:"server" yaml:"server"`
+	AuthInfo  string `json:"authInfo" yaml:"authInfo"`
+	AuthType  string `json:"authType" yaml:"authType"`
+	// File defines the context entry
+	File    string        `json:"file" yaml:"file"`
+	Sources StatusSources `json:"sources" yaml:"sources"`
+}
+
+// StatusSources are kubeconfig files values of Status come from, empty when a value is not set by any file
+type StatusSources struct {
+	Context   string `json:"context" yaml:"context"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Cluster   string `json:"cluster" yaml:"cluster"`
+	AuthInfo  string `json:"authInfo" yaml:"authInfo"`
+}
+
+// CurrentStatus returns details of current context, its cluster and user
+func CurrentStatus() (*Status, error) {
+	ca := clientcmd.NewDefaultPathOptions()
+	cfg, err := ca.GetStartingConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get starting config: %v", err)
+	}
+
+	if len(cfg.CurrentContext) == 0 {
+		return nil, errors.New("current context is not set")
+	}
+
+	ctx, err := contextOf(cfg, ca.GetLoadingPrecedence(), cfg.CurrentContext, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkReferences(cfg, cfg.CurrentContext, ctx); err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		Context:   cfg.CurrentContext,
+		Namespace: ctx.Namespace,
+		Cluster:   ctx.Cluster,
+		AuthInfo:  ctx.AuthInfo,
+		AuthType:  "none",
+		File:      ctx.LocationOfOrigin,
+	}
+	if len(status.Namespace) == 0 {
+		status.Namespace = defaultNamespace
+	} else {
+		status.Sources.Namespace = ctx.LocationOfOrigin
+	}
+
+	if cluster, ok := cfg.Clusters[ctx.Cluster]; ok {
+		status.Server = cluster.Server
+		status.Sources.Cluster = cluster.LocationOfOrigin
+	}
+
+	if authInfo, ok := cfg.AuthInfos[ctx.AuthInfo]; ok {
+		status.AuthType = authType(authInfo)
+		status.Sources.AuthInfo = authInfo.LocationOfOrigin
+	}
+
+	status.Sources.Context, err = currentContextFile(ca.GetLoadingPrecedence())
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// currentContextFile returns the first file setting current-context, which takes precedence over the others
+func currentContextFile(files []string) (string, error) {
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return "", fmt.Errorf("failed to read kube config %s: %v", f, err)
+		}
+
+		var c minimalConfig
+		if err := yaml.Unmarshal(content, &c); err != nil {
+			return "", fmt.Errorf("failed to parse kube config %s: %v", f, err)
+		}
+
+		if len(c.CurrentContext) > 0 {
+			return f, nil
+		}
+	}
+
+	return "", nil
+}
+
+func authType(authInfo *api.AuthInfo) string {
+	switch {
+	case authInfo.Exec != nil:
+		return fmt.Sprintf("exec (%s)", authInfo.Exec.Command)
+	case authInfo.AuthProvider != nil:
+		return fmt.Sprintf("auth-provider (%s)", authInfo.AuthProvider.Name)
+	case len(authInfo.Token) > 0 || len(authInfo.TokenFile) > 0:
+		return "token"
+	case len(authInfo.ClientCertificate) > 0 || len(authInfo.ClientCertificateData) > 0:
+		return "client-certificate"
+	case len(authInfo.Username) > 0:
+		return "basic"
+	default:
+		return "none"
+	}
+}
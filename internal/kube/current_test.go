@@ -0,0 +1,107 @@
+//go:build unit
+
+package kube
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestCurrentContextAndNamespace(t *testing.T) {
+	t.Run("return default namespace when current context does not set namespace", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-3")
+		defer os.Unsetenv("KUBECONFIG")
+
+		currentContext, namespace, err := CurrentContextAndNamespace()
+
+		require.NoError(t, err)
+		require.Equal(t, "context-2", currentContext)
+		require.Equal(t, "default", namespace)
+	})
+
+	t.Run("return namespace from first file that defines current context", func(t *testing.T) {
+		config1Path := copyFileToTmp(t, "testdata/kubeconfig-1")
+		defer os.Remove(config1Path)
+		config2Path := copyFileToTmp(t, "testdata/kubeconfig-2")
+		defer os.Remove(config2Path)
+
+		os.Setenv("KUBECONFIG", fmt.Sprintf("%s:%s:not-existing", config1Path, config2Path))
+		defer os.Unsetenv("KUBECONFIG")
+
+		require.NoError(t, SwitchContextAndNamespace("context-2", "ns2"))
+
+		currentContext, namespace, err := CurrentContextAndNamespace()
+
+		require.NoError(t, err)
+		require.Equal(t, "context-2", currentContext)
+		require.Equal(t, "ns2", namespace)
+	})
+}
+
+func TestCurrentStatus(t *testing.T) {
+	t.Run("return error when current context is not set", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-1")
+		defer os.Unsetenv("KUBECONFIG")
+
+		_, err := CurrentStatus()
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "current context is not set")
+	})
+
+	t.Run("return error when current context does not exist in config files", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-4")
+		defer os.Unsetenv("KUBECONFIG")
+
+		_, err := CurrentStatus()
+
+		var notFound *ContextNotFoundError
+		require.ErrorAs(t, err, &notFound)
+		require.True(t, notFound.Current)
+	})
+
+	t.Run("return error when cluster or user of current context does not exist", func(t *testing.T) {
+		for ctx, expected := range map[string]error{
+			"context-without-cluster": &ClusterNotFoundError{Context: "context-without-cluster", Cluster: "cluster-2"},
+			"context-without-user":    &UserNotFoundError{Context: "context-without-user", User: "user-2"},
+		} {
+			configPath := copyFileToTmp(t, "testdata/kubeconfig-4")
+			defer os.Remove(configPath)
+			content, err := os.ReadFile(configPath)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(configPath, bytes.ReplaceAll(content, []byte("deleted-context"), []byte(ctx)), 0644))
+			os.Setenv("KUBECONFIG", configPath)
+			defer os.Unsetenv("KUBECONFIG")
+
+			_, err = CurrentStatus()
+
+			require.Equal(t, expected, err)
+		}
+	})
+
+	t.Run("return details of current context", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-3")
+		defer os.Unsetenv("KUBECONFIG")
+
+		status, err := CurrentStatus()
+
+		require.NoError(t, err)
+		require.Equal(t, &Status{
+			Context:   "context-2",
+			Namespace: "default",
+			Cluster:   "cluster-1",
+			Server:    "https://some-kube-api:8443",
+			AuthInfo:  "user-2",
+			AuthType:  "client-certificate",
+			File:      "testdata/kubeconfig-3",
+			Sources: StatusSources{
+				Context:  "testdata/kubeconfig-3",
+				Cluster:  "testdata/kubeconfig-3",
+				AuthInfo: "testdata/kubeconfig-3",
+			},
+		}, status)
+	})
+}
@@ -0,0 +1,90 @@
+package kube
+
+import (
+	"fmt"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"os"
+	"strings"
+)
+
+// ContextNotFoundError is returned when a context doesn't exist in kubeconfig files.
+// Current is true when the missing context is the current context, .e.g. after it's deleted from kubeconfig
+type ContextNotFoundError struct {
+	Name    string
+	Current bool
+}
+
+func (e *ContextNotFoundError) Error() string {
+	if e.Current {
+		return fmt.Sprintf("current context %s does not exist in kube config file(s), run `kz ctx` to switch to an existing context", e.Name)
+	}
+
+	return fmt.Sprintf("context with name %s does not exist in kube config file(s), run `kz ctx sync` to update tracked contexts", e.Name)
+}
+
+// ClusterNotFoundError is returned when the cluster referred to by a context doesn't exist in kubeconfig files
+type ClusterNotFoundError struct {
+	Context string
+	Cluster string
+}
+
+func (e *ClusterNotFoundError) Error() string {
+	return fmt.Sprintf("cluster %s of context %s does not exist in kube config file(s), add the cluster or run `kubectl config set-context %s --cluster=<cluster>` to use an existing one", e.Cluster, e.Context, e.Context)
+}
+
+// UserNotFoundError is returned when the user referred to by a context doesn't exist in kubeconfig files
+type UserNotFoundError struct {
+	Context string
+	User    string
+}
+
+func (e *UserNotFoundError) Error() string {
+	return fmt.Sprintf("user %s of context %s does not exist in kube config file(s), add the user or run `kubectl config set-context %s --user=<user>` to use an existing one", e.User, e.Context, e.Context)
+}
+
+// EmptyConfigError is returned when kubeconfig files don't define any contexts, Files are the existing kubeconfig files
+type EmptyConfigError struct {
+	Files []string
+}
+
+func (e *EmptyConfigError) Error() string {
+	if len(e.Files) == 0 {
+		return "no kube config files found, set KUBECONFIG or create ~/.kube/config"
+	}
+
+	return fmt.Sprintf("no contexts defined in kube config file(s) %s, check that KUBECONFIG points to the right files", strings.Join(e.Files, ", "))
+}
+
+// contextOf returns the context with the given name, or a typed error explaining why it's not available
+func contextOf(cfg *api.Config, files []string, name string, current bool) (*api.Context, error) {
+	if len(cfg.Contexts) == 0 {
+		var existing []string
+		for _, f := range files {
+			if _, err := os.Stat(f); err == nil {
+				existing = append(existing, f)
+			}
+		}
+
+		return nil, &EmptyConfigError{Files: existing}
+	}
+
+	ctx, ok := cfg.Contexts[name]
+	if !ok {
+		return nil, &ContextNotFoundError{Name: name, Current: current}
+	}
+
+	return ctx, nil
+}
+
+// checkReferences returns an error when the cluster or user referred to by the context doesn't exist
+func checkReferences(cfg *api.Config, name string, ctx *api.Context) error {
+	if _, ok := cfg.Clusters[ctx.Cluster]; len(ctx.Cluster) > 0 && !ok {
+		return &ClusterNotFoundError{Context: name, Cluster: ctx.Cluster}
+	}
+
+	if _, ok := cfg.AuthInfos[ctx.AuthInfo]; len(ctx.AuthInfo) > 0 && !ok {
+		return &UserNotFoundError{Context: name, User: ctx.AuthInfo}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"os"
+)
+
+// NewPinnedConfig writes a temporary minimal kubeconfig that contains only the given context together with its cluster and user,
+// with the context set as current context. The namespace of the context is overridden when namespace is not empty.
+// Callers are responsible for removing the returned file
+func NewPinnedConfig(ctx string, namespace string) (string, error) {
+	if len(ctx) == 0 {
+		return "", errors.New("context to pin is required")
+	}
+
+	// load with default loading rules so that relative paths are resolved against the files defining them
+	cfg, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load kube config: %v", err)
+	}
+
+	c, err := contextOf(cfg, clientcmd.NewDefaultPathOptions().GetLoadingPrecedence(), ctx, false)
+	if err != nil {
+		return "", err
+	}
+
+	// a pinned config is only usable with both cluster and user of the context
+	if err := checkReferences(cfg, ctx, c); err != nil {
+		return "", err
+	}
+
+	cfg.CurrentContext = ctx
+	if len(namespace) > 0 {
+		c.Namespace = namespace
+	}
+
+	if err := api.MinifyConfig(cfg); err != nil {
+		return "", fmt.Errorf("failed to minify kube config for context %s: %v", ctx, err)
+	}
+
+	dir, err := sessionDirectory()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(dir, "pinned-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to create pinned kube config in %s: %v", dir, err)
+	}
+	f.Close()
+
+	if err := clientcmd.WriteToFile(*cfg, f.Name()); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write pinned kube config %s: %v", f.Name(), err)
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,68 @@
+//go:build unit
+
+package kube
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestNewPinnedConfig(t *testing.T) {
+	t.Run("return error when context to pin not exists in config files", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-1")
+		defer os.Unsetenv("KUBECONFIG")
+
+		_, err := NewPinnedConfig("context-3", "")
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "context with name context-3 does not exist in kube config file(s)")
+	})
+
+	t.Run("return error when cluster of context to pin does not exist", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-4")
+		defer os.Unsetenv("KUBECONFIG")
+
+		_, err := NewPinnedConfig("context-without-cluster", "")
+
+		var notFound *ClusterNotFoundError
+		require.ErrorAs(t, err, &notFound)
+		require.ErrorContains(t, err, "kubectl config set-context context-without-cluster --cluster=<cluster>")
+	})
+
+	t.Run("write minimal config with only given context, its cluster and user", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-1")
+		defer os.Unsetenv("KUBECONFIG")
+		os.Setenv("XDG_RUNTIME_DIR", os.TempDir())
+		defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+		pinned, err := NewPinnedConfig("context-2", "ns1")
+		require.NoError(t, err)
+		defer os.Remove(pinned)
+
+		content, err := os.ReadFile(pinned)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "current-context: context-2")
+		require.Contains(t, string(content), "namespace: ns1")
+		require.Contains(t, string(content), "name: cluster-1")
+		require.Contains(t, string(content), "name: user-2")
+		require.NotContains(t, string(content), "context-1")
+		require.NotContains(t, string(content), "user-1")
+	})
+
+	t.Run("keep namespace of context when namespace is not provided", func(t *testing.T) {
+		os.Setenv("KUBECONFIG", "testdata/kubeconfig-1")
+		defer os.Unsetenv("KUBECONFIG")
+		os.Setenv("XDG_RUNTIME_DIR", os.TempDir())
+		defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+		pinned, err := NewPinnedConfig("context-1", "")
+		require.NoError(t, err)
+		defer os.Remove(pinned)
+
+		content, err := os.ReadFile(pinned)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "current-context: context-1")
+		require.NotContains(t, string(content), "namespace:")
+	})
+}
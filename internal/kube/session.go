@@ -0,0 +1,122 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SessionEnvVar holds the path to the session overlay of the current shell
+const SessionEnvVar = "KZ_SESSION_KUBECONFIG"
+
+// Session is a per-shell kubeconfig overlay holding only current context and namespace.
+// The overlay is put in front of the real kubeconfig files in KUBECONFIG so that switches in one shell don't affect others
+type Session struct {
+	Overlay    string
+	Kubeconfig string
+}
+
+// StartSession creates a session overlay seeded with current context and namespace.
+// When a session is already active, e.g. in a shell started from a session shell, the new overlay is seeded from it
+// and replaces it in the returned KUBECONFIG value
+func StartSession() (*Session, error) {
+	ca := clientcmd.NewDefaultPathOptions()
+	cfg, err := ca.GetStartingConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get starting config: %v", err)
+	}
+
+	dir, err := sessionDirectory()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp(dir, "session-*.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session overlay in %s: %v", dir, err)
+	}
+	f.Close()
+
+	if err := writeOverlay(f.Name(), cfg); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return &Session{
+		Overlay:    f.Name(),
+		Kubeconfig: strings.Join(append([]string{f.Name()}, realConfigFiles(ca)...), string(filepath.ListSeparator)),
+	}, nil
+}
+
+// EndSession removes the overlay of the active session and returns KUBECONFIG value without the overlay
+func EndSession() (string, error) {
+	overlay, ok := ActiveSession()
+	if !ok {
+		return "", errors.New("no kz session is active in this shell")
+	}
+
+	if err := os.Remove(overlay); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to remove session overlay %s: %v", overlay, err)
+	}
+
+	return strings.Join(realConfigFiles(clientcmd.NewDefaultPathOptions()), string(filepath.ListSeparator)), nil
+}
+
+// ActiveSession returns the overlay path when the current shell has an active session,
+// i.e. the overlay is still the first file in KUBECONFIG
+func ActiveSession() (string, bool) {
+	overlay := os.Getenv(SessionEnvVar)
+	if len(overlay) == 0 {
+		return "", false
+	}
+
+	files := clientcmd.NewDefaultPathOptions().GetEnvVarFiles()
+	if len(files) == 0 || files[0] != overlay {
+		return "", false
+	}
+
+	return overlay, true
+}
+
+// realConfigFiles returns kubeconfig files in loading precedence, excluding overlay of the active session
+func realConfigFiles(ca *clientcmd.PathOptions) []string {
+	files := ca.GetLoadingPrecedence()
+	if _, ok := ActiveSession(); ok {
+		return files[1:]
+	}
+
+	return files
+}
+
+// writeOverlay writes a kubeconfig holding only current context and its entry,
+// so that it overrides other kubeconfig files when it's put in front of them in KUBECONFIG
+func writeOverlay(overlay string, cfg *api.Config) error {
+	o := api.NewConfig()
+	o.CurrentContext = cfg.CurrentContext
+	if ctx, ok := cfg.Contexts[cfg.CurrentContext]; ok {
+		o.Contexts[cfg.CurrentContext] = ctx.DeepCopy()
+	}
+
+	if err := clientcmd.WriteToFile(*o, overlay); err != nil {
+		return fmt.Errorf("failed to write kube config overlay %s: %v", overlay, err)
+	}
+
+	return nil
+}
+
+func sessionDirectory() (string, error) {
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("kz-%d", os.Getuid()))
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); len(runtimeDir) > 0 {
+		dir = filepath.Join(runtimeDir, "kz")
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create session directory %s: %v", dir, err)
+	}
+
+	return dir, nil
+}
@@ -0,0 +1,124 @@
+//go:build unit
+
+package kube
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestStartSession(t *testing.T) {
+	t.Run("create overlay with current context and put it in front of real kubeconfig files", func(t *testing.T) {
+		configPath := copyFileToTmp(t, "testdata/kubeconfig-3")
+		defer os.Remove(configPath)
+
+		os.Setenv("KUBECONFIG", configPath)
+		defer os.Unsetenv("KUBECONFIG")
+		os.Setenv("XDG_RUNTIME_DIR", os.TempDir())
+		defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+		session, err := StartSession()
+		require.NoError(t, err)
+		defer os.Remove(session.Overlay)
+
+		require.Equal(t, session.Overlay+":"+configPath, session.Kubeconfig)
+		content, err := os.ReadFile(session.Overlay)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "current-context: context-2")
+		require.NotContains(t, string(content), "context-1")
+	})
+}
+
+func TestStartSessionWithActiveSession(t *testing.T) {
+	t.Run("seed new overlay from active session and replace it in kubeconfig", func(t *testing.T) {
+		configPath := copyFileToTmp(t, "testdata/kubeconfig-3")
+		defer os.Remove(configPath)
+
+		os.Setenv("KUBECONFIG", configPath)
+		defer os.Unsetenv("KUBECONFIG")
+		os.Setenv("XDG_RUNTIME_DIR", os.TempDir())
+		defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+		parent, err := StartSession()
+		require.NoError(t, err)
+		defer os.Remove(parent.Overlay)
+
+		os.Setenv("KUBECONFIG", parent.Kubeconfig)
+		os.Setenv(SessionEnvVar, parent.Overlay)
+		defer os.Unsetenv(SessionEnvVar)
+		require.NoError(t, SwitchContextTo("context-1"))
+
+		child, err := StartSession()
+		require.NoError(t, err)
+		defer os.Remove(child.Overlay)
+
+		require.Equal(t, child.Overlay+":"+configPath, child.Kubeconfig)
+		content, err := os.ReadFile(child.Overlay)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "current-context: context-1")
+	})
+}
+
+func TestSwitchWithActiveSession(t *testing.T) {
+	t.Run("only update session overlay when switching context and namespace", func(t *testing.T) {
+		configPath := copyFileToTmp(t, "testdata/kubeconfig-3")
+		defer os.Remove(configPath)
+		original, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+
+		os.Setenv("KUBECONFIG", configPath)
+		defer os.Unsetenv("KUBECONFIG")
+		os.Setenv("XDG_RUNTIME_DIR", os.TempDir())
+		defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+		session, err := StartSession()
+		require.NoError(t, err)
+		defer os.Remove(session.Overlay)
+
+		os.Setenv("KUBECONFIG", session.Kubeconfig)
+		os.Setenv(SessionEnvVar, session.Overlay)
+		defer os.Unsetenv(SessionEnvVar)
+
+		require.NoError(t, SwitchContextAndNamespace("context-1", "ns1"))
+
+		content, err := os.ReadFile(session.Overlay)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "current-context: context-1")
+		require.Contains(t, string(content), "namespace: ns1")
+
+		afterSwitch, err := os.ReadFile(configPath)
+		require.NoError(t, err)
+		require.Equal(t, string(original), string(afterSwitch))
+
+		currentContext, err := CurrentContext()
+		require.NoError(t, err)
+		require.Equal(t, "context-1", currentContext)
+	})
+}
+
+func TestEndSession(t *testing.T) {
+	t.Run("remove overlay and return kubeconfig without overlay", func(t *testing.T) {
+		configPath := copyFileToTmp(t, "testdata/kubeconfig-3")
+		defer os.Remove(configPath)
+
+		os.Setenv("KUBECONFIG", configPath)
+		defer os.Unsetenv("KUBECONFIG")
+		os.Setenv("XDG_RUNTIME_DIR", os.TempDir())
+		defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+		session, err := StartSession()
+		require.NoError(t, err)
+
+		os.Setenv("KUBECONFIG", session.Kubeconfig)
+		os.Setenv(SessionEnvVar, session.Overlay)
+		defer os.Unsetenv(SessionEnvVar)
+
+		kubeconfig, err := EndSession()
+		require.NoError(t, err)
+
+		require.Equal(t, configPath, kubeconfig)
+		_, err = os.Stat(session.Overlay)
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,91 @@
+package kube
+
+import (
+	"errors"
+	"fmt"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+	"os"
+	"path/filepath"
+)
+
+var stateFile string
+
+// SetStateFile designates a writable kubeconfig file receiving current context and namespace overrides,
+// so that other kubeconfig files, .e.g. generated by cloud tooling, are never modified. An empty path restores the default behaviour
+// of writing to the files defining the changed values
+func SetStateFile(path string) {
+	stateFile = path
+}
+
+// writeStateFile writes current context and namespace overrides to the state file, which has to come first in KUBECONFIG for them to take precedence.
+// Kubeconfig files don't merge fields of a context, so an override is a copy of the context entry from the other files with a different namespace.
+// Overrides are refreshed from the other files on every write, and dropped when their namespace matches the other files
+// or the context doesn't exist in them anymore, .e.g. after cloud tooling regenerated its kubeconfig
+func writeStateFile(ca *clientcmd.PathOptions, cfg *api.Config) error {
+	files := ca.GetLoadingPrecedence()
+	if len(files) == 0 || !sameFile(files[0], stateFile) {
+		return fmt.Errorf("kube config state file %s must be the first file in KUBECONFIG to take precedence, .e.g. export KUBECONFIG=%s:$KUBECONFIG", stateFile, stateFile)
+	}
+
+	state, err := clientcmd.LoadFromFile(stateFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load kube config state file %s: %v", stateFile, err)
+	}
+
+	underlying, err := (&clientcmd.ClientConfigLoadingRules{Precedence: files[1:]}).Load()
+	if err != nil {
+		return fmt.Errorf("failed to load kube config files other than state file %s: %v", stateFile, err)
+	}
+
+	overridden := []string{cfg.CurrentContext}
+	if state != nil {
+		for name := range state.Contexts {
+			overridden = append(overridden, name)
+		}
+	}
+
+	o := api.NewConfig()
+	o.CurrentContext = cfg.CurrentContext
+	for _, name := range overridden {
+		ctx, ok := cfg.Contexts[name]
+		if !ok {
+			continue
+		}
+
+		original, ok := underlying.Contexts[name]
+		if !ok || original.Namespace == ctx.Namespace {
+			continue
+		}
+
+		override := original.DeepCopy()
+		override.Namespace = ctx.Namespace
+		o.Contexts[name] = override
+	}
+
+	return withBackups([]string{stateFile}, func() error {
+		if err := os.MkdirAll(filepath.Dir(stateFile), 0700); err != nil {
+			return fmt.Errorf("failed to create directory of kube config state file %s: %v", stateFile, err)
+		}
+
+		if err := clientcmd.WriteToFile(*o, stateFile); err != nil {
+			return fmt.Errorf("failed to write kube config state file %s: %v", stateFile, err)
+		}
+
+		return nil
+	})
+}
+
+func sameFile(left string, right string) bool {
+	l, err := filepath.Abs(left)
+	if err != nil {
+		return false
+	}
+
+	r, err := filepath.Abs(right)
+	if err != nil {
+		return false
+	}
+
+	return l == r
+}
@@ -0,0 +1,102 @@
+//go:build unit
+
+package kube
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateFile(t *testing.T) {
+	t.Run("write current context and namespace to state file without touching other files", func(t *testing.T) {
+		stateFile, generated := setupStateFile(t)
+		original, err := os.ReadFile(generated)
+		require.NoError(t, err)
+
+		require.NoError(t, SwitchContextAndNamespace("context-1", "ns1"))
+
+		content, err := os.ReadFile(generated)
+		require.NoError(t, err)
+		require.Equal(t, original, content)
+
+		status, err := CurrentStatus()
+		require.NoError(t, err)
+		require.Equal(t, "context-1", status.Context)
+		require.Equal(t, "ns1", status.Namespace)
+		require.Equal(t, stateFile, status.Sources.Context)
+		require.Equal(t, stateFile, status.Sources.Namespace)
+		require.Equal(t, generated, status.Sources.Cluster)
+		require.Equal(t, generated, status.Sources.AuthInfo)
+	})
+
+	t.Run("keep shadowing namespaces of contexts switched to before", func(t *testing.T) {
+		setupStateFile(t)
+
+		require.NoError(t, SwitchContextAndNamespace("context-1", "ns1"))
+		require.NoError(t, SwitchContextTo("context-2"))
+		require.NoError(t, SwitchNamespaceTo("ns2"))
+
+		namespace, err := NamespaceOf("context-1")
+		require.NoError(t, err)
+		require.Equal(t, "ns1", namespace)
+		namespace, err = NamespaceOf("context-2")
+		require.NoError(t, err)
+		require.Equal(t, "ns2", namespace)
+	})
+
+	t.Run("only store namespaces that differ from other files", func(t *testing.T) {
+		stateFile, _ := setupStateFile(t)
+
+		require.NoError(t, SwitchContextAndNamespace("context-1", "ns1"))
+		require.NoError(t, SwitchContextAndNamespace("context-1", ""))
+		require.NoError(t, SwitchContextTo("context-2"))
+
+		state, err := clientcmd.LoadFromFile(stateFile)
+		require.NoError(t, err)
+		require.Equal(t, "context-2", state.CurrentContext)
+		require.Empty(t, state.Contexts)
+	})
+
+	t.Run("refresh overrides from other files", func(t *testing.T) {
+		stateFile, generated := setupStateFile(t)
+		require.NoError(t, SwitchContextAndNamespace("context-1", "ns1"))
+
+		// cloud tooling regenerates its kubeconfig with context-1 using another user
+		content, err := os.ReadFile(generated)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(generated, bytes.Replace(content, []byte("user: user-1"), []byte("user: user-2"), 1), 0644))
+		require.NoError(t, SwitchContextTo("context-2"))
+
+		state, err := clientcmd.LoadFromFile(stateFile)
+		require.NoError(t, err)
+		require.Equal(t, "user-2", state.Contexts["context-1"].AuthInfo)
+		require.Equal(t, "ns1", state.Contexts["context-1"].Namespace)
+		require.Empty(t, state.AuthInfos)
+		require.Empty(t, state.Clusters)
+	})
+
+	t.Run("return error when state file is not the first kubeconfig file", func(t *testing.T) {
+		stateFile, generated := setupStateFile(t)
+		t.Setenv("KUBECONFIG", generated+string(filepath.ListSeparator)+stateFile)
+
+		err := SwitchContextTo("context-1")
+
+		require.ErrorContains(t, err, "must be the first file in KUBECONFIG")
+	})
+}
+
+func setupStateFile(t *testing.T) (string, string) {
+	generated := copyFileToTmp(t, "testdata/kubeconfig-3")
+	t.Cleanup(func() { os.Remove(generated) })
+	stateFile := filepath.Join(t.TempDir(), "state", "kubeconfig")
+	t.Setenv("KUBECONFIG", stateFile+string(filepath.ListSeparator)+generated)
+	t.Setenv(SessionEnvVar, "")
+
+	SetStateFile(stateFile)
+	t.Cleanup(func() { SetStateFile("") })
+	return stateFile, generated
+}
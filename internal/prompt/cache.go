@@ -0,0 +1,138 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxCacheEntries bounds the cache since every session overlay produces its own entry
+const maxCacheEntries = 16
+
+type cache struct {
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+type cacheEntry struct {
+	Stamps    string    `json:"stamps"`
+	Output    string    `json:"output"`
+	WrittenAt time.Time `json:"writtenAt"`
+}
+
+func cacheKey(name string, files []string) string {
+	return fmt.Sprintf("%s|%s", name, strings.Join(files, string(filepath.ListSeparator)))
+}
+
+// fileStamps identifies the current version of given files by modification time and size
+func fileStamps(files []string) string {
+	var stamps []string
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			stamps = append(stamps, "-")
+			continue
+		}
+
+		stamps = append(stamps, fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()))
+	}
+
+	return strings.Join(stamps, ",")
+}
+
+func cacheLocation() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "kz", "prompt.json"), nil
+}
+
+func loadCache() *cache {
+	c := &cache{Entries: map[string]cacheEntry{}}
+	location, err := cacheLocation()
+	if err != nil {
+		return c
+	}
+
+	content, err := os.ReadFile(location)
+	if err != nil {
+		return c
+	}
+
+	if err := json.Unmarshal(content, c); err != nil || c.Entries == nil {
+		return &cache{Entries: map[string]cacheEntry{}}
+	}
+
+	return c
+}
+
+func (c *cache) get(key string, stamps string) (string, bool) {
+	entry, ok := c.Entries[key]
+	if !ok || entry.Stamps != stamps {
+		return "", false
+	}
+
+	return entry.Output, true
+}
+
+func (c *cache) put(key string, stamps string, output string) {
+	c.Entries[key] = cacheEntry{
+		Stamps:    stamps,
+		Output:    output,
+		WrittenAt: time.Now(),
+	}
+
+	if len(c.Entries) <= maxCacheEntries {
+		return
+	}
+
+	keys := make([]string, 0, len(c.Entries))
+	for k := range c.Entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.Entries[keys[i]].WrittenAt.Before(c.Entries[keys[j]].WrittenAt)
+	})
+	for _, k := range keys[:len(keys)-maxCacheEntries] {
+		delete(c.Entries, k)
+	}
+}
+
+// save writes to a temporary file then renames it so that concurrent prompts never read a partially written cache
+func (c *cache) save() error {
+	location, err := cacheLocation()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(location), 0700); err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(location), "prompt-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), location)
+}
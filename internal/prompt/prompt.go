@@ -0,0 +1,145 @@
+package prompt
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/hpcsc/kz/internal/kube"
+	"strings"
+	"text/template"
+)
+
+const DefaultFormat = "{{color .Color .Context}}/{{.Namespace}}"
+
+type Options struct {
+	Format  string
+	NoColor bool
+}
+
+// Segment is the data available to prompt format
+type Segment struct {
+	// Context is the display name of current context, i.e. name from prompt settings or the full name when there's none
+	Context     string
+	FullContext string
+	Namespace   string
+	// Color is the color from prompt settings, protected contexts are red unless a color is set
+	Color     string
+	Protected bool
+}
+
+var colors = map[string]color.Attribute{
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+}
+
+// Render renders the prompt segment for current context and namespace.
+// The output is cached until one of kubeconfig files or kz configuration files changes so that it's cheap to call on every prompt
+func Render(opts Options) (string, error) {
+	return Cached(fmt.Sprintf("%s|%t", opts.Format, opts.NoColor), func(cfg *config.Config) (string, error) {
+		return render(opts, cfg)
+	})
+}
+
+// Cached returns the output of render for configuration layers and current context, cached under the name like prompt segments.
+// render is only called when one of kubeconfig files or kz configuration files has changed since the output was cached
+func Cached(name string, render func(cfg *config.Config) (string, error)) (string, error) {
+	layers, err := config.Layers()
+	if err != nil {
+		return "", err
+	}
+
+	files := kube.ConfigFiles()
+	for _, l := range layers {
+		files = append(files, l.Location)
+	}
+	key := cacheKey(name, files)
+	stamps := fileStamps(files)
+
+	c := loadCache()
+	if output, ok := c.get(key, stamps); ok {
+		return output, nil
+	}
+
+	cfg, err := config.LoadLayers(layers)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := render(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	c.put(key, stamps, output)
+	// failing to cache only makes the next prompt slower
+	_ = c.save()
+
+	return output, nil
+}
+
+func render(opts Options, cfg *config.Config) (string, error) {
+	currentContext, namespace, err := kube.CurrentContextAndNamespace()
+	if err != nil {
+		return "", err
+	}
+
+	if len(currentContext) == 0 {
+		return "", nil
+	}
+
+	segment := Segment{
+		Context:     currentContext,
+		FullContext: currentContext,
+		Namespace:   namespace,
+		Protected:   cfg.IsProtected(currentContext),
+	}
+	if segment.Protected {
+		segment.Color = "red"
+	}
+	if p, ok := cfg.PromptContextFor(currentContext); ok {
+		if len(p.Name) > 0 {
+			segment.Context = p.Name
+		}
+		if len(p.Color) > 0 {
+			segment.Color = p.Color
+		}
+	}
+
+	tmpl, err := template.New("prompt").
+		Funcs(template.FuncMap{"color": colorFunc(opts.NoColor)}).
+		Parse(opts.Format)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt format: %v", err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, segment); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %v", err)
+	}
+
+	return b.String(), nil
+}
+
+// colorFunc always colors output when color is enabled since prompt output is usually captured by the shell instead of written to a terminal
+func colorFunc(noColor bool) func(string, string) (string, error) {
+	return func(name string, text string) (string, error) {
+		if noColor || len(name) == 0 {
+			return text, nil
+		}
+
+		attribute, ok := colors[name]
+		if !ok {
+			return "", fmt.Errorf("unsupported color '%s'", name)
+		}
+
+		c := color.New(attribute)
+		c.EnableColor()
+		return c.Sprint(text), nil
+	}
+}
@@ -0,0 +1,146 @@
+//go:build unit
+
+package prompt
+
+import (
+	"github.com/hpcsc/kz/internal/config"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+contexts:
+- context:
+    cluster: cluster-1
+    user: user-1
+    namespace: payments
+  name: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+- context:
+    cluster: cluster-1
+    user: user-1
+  name: staging
+current-context: arn:aws:eks:eu-west-1:123456789012:cluster/prod
+`
+
+const kzConfig = `prompt:
+  contexts:
+    - match: "*cluster/prod"
+      name: prod
+      color: red
+`
+
+func TestRender(t *testing.T) {
+	t.Run("use display name and color from prompt settings", func(t *testing.T) {
+		setupPromptEnvironment(t, kubeconfig, kzConfig)
+
+		output, err := Render(Options{Format: DefaultFormat})
+
+		require.NoError(t, err)
+		require.Equal(t, "\x1b[31mprod\x1b[0m/payments", output)
+	})
+
+	t.Run("render fields without color", func(t *testing.T) {
+		setupPromptEnvironment(t, kubeconfig, kzConfig)
+
+		output, err := Render(Options{Format: "{{color .Color .Context}} {{.FullContext}}", NoColor: true})
+
+		require.NoError(t, err)
+		require.Equal(t, "prod arn:aws:eks:eu-west-1:123456789012:cluster/prod", output)
+	})
+
+	t.Run("use full context name and default namespace when there are no prompt settings", func(t *testing.T) {
+		setupPromptEnvironment(t, `current-context: staging
+contexts:
+- context:
+    cluster: cluster-1
+  name: staging
+`, "")
+
+		output, err := Render(Options{Format: DefaultFormat})
+
+		require.NoError(t, err)
+		require.Equal(t, "staging/default", output)
+	})
+
+	t.Run("show protected contexts in red unless a color is set", func(t *testing.T) {
+		setupPromptEnvironment(t, kubeconfig, `protected:
+  - "*prod*"
+`)
+
+		output, err := Render(Options{Format: `{{if .Protected}}!{{end}}{{color .Color .Context}}`})
+
+		require.NoError(t, err)
+		require.Equal(t, "!\x1b[31marn:aws:eks:eu-west-1:123456789012:cluster/prod\x1b[0m", output)
+	})
+
+	t.Run("return error when color is not supported", func(t *testing.T) {
+		setupPromptEnvironment(t, kubeconfig, kzConfig)
+
+		_, err := Render(Options{Format: `{{color "pink" .Context}}`})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported color 'pink'")
+	})
+
+	t.Run("render again when kubeconfig changes", func(t *testing.T) {
+		kubeconfigPath := setupPromptEnvironment(t, kubeconfig, kzConfig)
+
+		output, err := Render(Options{Format: "{{.Context}}", NoColor: true})
+		require.NoError(t, err)
+		require.Equal(t, "prod", output)
+
+		// make sure modification time changes on file systems with coarse timestamps
+		require.NoError(t, os.WriteFile(kubeconfigPath, []byte("current-context: staging\n"), 0644))
+		later := time.Now().Add(time.Second)
+		require.NoError(t, os.Chtimes(kubeconfigPath, later, later))
+
+		output, err = Render(Options{Format: "{{.Context}}", NoColor: true})
+		require.NoError(t, err)
+		require.Equal(t, "staging", output)
+	})
+}
+
+func TestCached(t *testing.T) {
+	t.Run("render once until configuration files change", func(t *testing.T) {
+		setupPromptEnvironment(t, kubeconfig, kzConfig)
+		calls := 0
+		render := func(cfg *config.Config) (string, error) {
+			calls++
+			return cfg.Prompt.Contexts[0].Name, nil
+		}
+
+		for i := 0; i < 2; i++ {
+			output, err := Cached("name", render)
+			require.NoError(t, err)
+			require.Equal(t, "prod", output)
+		}
+		require.Equal(t, 1, calls)
+
+		_, err := Cached("other", render)
+		require.NoError(t, err)
+		require.Equal(t, 2, calls)
+	})
+}
+
+func setupPromptEnvironment(t *testing.T, kubeconfigContent string, kzConfigContent string) string {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", path.Join(dir, ".cache"))
+	kzConfigPath := path.Join(dir, "config.yml")
+	t.Setenv(config.LocationEnvVar, kzConfigPath)
+	t.Setenv(config.SystemLocationEnvVar, path.Join(dir, "system.yml"))
+
+	kubeconfigPath := path.Join(dir, "kubeconfig")
+	require.NoError(t, os.WriteFile(kubeconfigPath, []byte(kubeconfigContent), 0644))
+	t.Setenv("KUBECONFIG", kubeconfigPath)
+
+	if len(kzConfigContent) > 0 {
+		require.NoError(t, os.WriteFile(kzConfigPath, []byte(kzConfigContent), 0644))
+	}
+
+	return kubeconfigPath
+}
@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	PromptHook = "prompt"
+	ChpwdHook  = "chpwd"
+)
+
+//go:embed scripts
+var scripts embed.FS
+
+type InitOptions struct {
+	Hooks      []string
+	Session    bool
+	Completion bool
+}
+
+// Init returns the shell code to be evaluated by the given shell to set up kz shell integration
+func Init(s Shell, opts InitOptions) (string, error) {
+	data := struct {
+		PromptHook bool
+		ChpwdHook  bool
+		Session    bool
+		Completion string
+	}{
+		Session: opts.Session,
+	}
+
+	for _, h := range opts.Hooks {
+		switch h {
+		case PromptHook:
+			data.PromptHook = true
+		case ChpwdHook:
+			data.ChpwdHook = true
+		default:
+			return "", fmt.Errorf("unsupported hook '%s', supported hooks: %s, %s", h, PromptHook, ChpwdHook)
+		}
+	}
+
+	if opts.Completion {
+		completion, err := Completion(s)
+		if err != nil {
+			return "", err
+		}
+		data.Completion = strings.TrimSpace(completion)
+	}
+
+	tmpl, err := template.ParseFS(scripts, fmt.Sprintf("scripts/init.%s", s))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse init script for %s: %v", s, err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render init script for %s: %v", s, err)
+	}
+
+	return b.String(), nil
+}
+
+// Completion returns the script that registers completion of kz commands in the given shell
+func Completion(s Shell) (string, error) {
+	content, err := scripts.ReadFile(fmt.Sprintf("scripts/completion.%s", s))
+	if err != nil {
+		return "", fmt.Errorf("failed to read completion script for %s: %v", s, err)
+	}
+
+	return string(content), nil
+}
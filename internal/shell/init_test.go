@@ -0,0 +1,45 @@
+//go:build unit
+
+package shell
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestInit(t *testing.T) {
+	t.Run("return error when hook is not supported", func(t *testing.T) {
+		_, err := Init(Bash, InitOptions{Hooks: []string{"preexec"}})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported hook 'preexec'")
+	})
+
+	t.Run("define wrapper function without hooks by default", func(t *testing.T) {
+		for _, s := range Supported {
+			script, err := Init(s, InitOptions{})
+
+			require.NoError(t, err)
+			require.Contains(t, script, "KZ_ENV_FILE")
+			require.NotContains(t, script, "kz hook")
+			require.NotContains(t, script, "kz session start")
+		}
+	})
+
+	t.Run("install requested hooks, completion and session", func(t *testing.T) {
+		for _, s := range Supported {
+			script, err := Init(s, InitOptions{
+				Hooks:      []string{PromptHook, ChpwdHook},
+				Session:    true,
+				Completion: true,
+			})
+
+			require.NoError(t, err)
+			require.Contains(t, script, "kz hook prompt")
+			require.Contains(t, script, "kz hook chpwd")
+			require.Contains(t, script, "--generate-bash-completion")
+			require.Contains(t, script, "kz session start")
+			require.Contains(t, script, "kz session end")
+		}
+	})
+}
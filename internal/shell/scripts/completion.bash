@@ -0,0 +1,23 @@
+_kz_bash_autocomplete() {
+  local cur words cword
+  COMPREPLY=()
+  if declare -F _init_completion >/dev/null 2>&1; then
+    _init_completion -n "=:" || return
+  else
+    # Macs have bash3 for which the bash-completion package doesn't include _init_completion
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    words=("${COMP_WORDS[@]}")
+    cword=${COMP_CWORD}
+  fi
+
+  local opts
+  if [[ "${cur}" == "-"* ]]; then
+    opts=$(command kz "${words[@]:1:$cword-1}" "${cur}" --generate-bash-completion 2>/dev/null)
+  else
+    opts=$(command kz "${words[@]:1:$cword-1}" --generate-bash-completion 2>/dev/null)
+  fi
+  COMPREPLY=($(compgen -W "${opts}" -- "${cur}"))
+  return 0
+}
+
+complete -o bashdefault -o default -o nospace -F _kz_bash_autocomplete kz
@@ -0,0 +1,11 @@
+function __kz_complete
+    set -l args (commandline -opc)
+    set -l current (commandline -ct)
+    if string match -q -- '-*' $current
+        command kz $args[2..-1] $current --generate-bash-completion 2>/dev/null
+    else
+        command kz $args[2..-1] --generate-bash-completion 2>/dev/null
+    end
+end
+
+complete -c kz -f -a '(__kz_complete)'
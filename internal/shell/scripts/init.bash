@@ -0,0 +1,71 @@
+# kz shell integration for bash
+# add `eval "$(kz init bash)"` to ~/.bashrc
+
+kz() {
+  # environment changes only apply to current shell, skip them in command substitution
+  if [ "${BASH_SUBSHELL:-0}" -gt 0 ]; then
+    command kz "$@"
+    return
+  fi
+
+  local __kz_env_file __kz_status
+  __kz_env_file="$(mktemp "${TMPDIR:-/tmp}/kz-env.XXXXXX")" || return 1
+  KZ_ENV_FILE="${__kz_env_file}" KZ_ENV_SHELL=bash command kz "$@"
+  __kz_status=$?
+  if [ -s "${__kz_env_file}" ]; then
+    . "${__kz_env_file}"
+  fi
+  rm -f "${__kz_env_file}"
+  return ${__kz_status}
+}
+{{- if .PromptHook }}
+
+# keep exit status of the last command for PS1 and other PROMPT_COMMAND entries
+__kz_hook_prompt() {
+  local __kz_last_status=$?
+  kz hook prompt
+  return ${__kz_last_status}
+}
+
+if [[ "${PROMPT_COMMAND:-}" != *__kz_hook_prompt* ]]; then
+  PROMPT_COMMAND="__kz_hook_prompt${PROMPT_COMMAND:+;${PROMPT_COMMAND}}"
+fi
+{{- end }}
+{{- if .ChpwdHook }}
+
+# bash has no chpwd hook, emulate it by checking working directory before each prompt
+__kz_hook_chpwd() {
+  local __kz_last_status=$?
+  if [ "${__kz_previous_pwd:-}" != "${PWD}" ]; then
+    __kz_previous_pwd="${PWD}"
+    kz hook chpwd
+  fi
+  return ${__kz_last_status}
+}
+
+if [[ "${PROMPT_COMMAND:-}" != *__kz_hook_chpwd* ]]; then
+  PROMPT_COMMAND="__kz_hook_chpwd${PROMPT_COMMAND:+;${PROMPT_COMMAND}}"
+fi
+{{- end }}
+{{- if .Completion }}
+
+{{ .Completion }}
+{{- end }}
+{{- if .Session }}
+
+kz session start >/dev/null
+{{- end }}
+{{- if or .Session .ChpwdHook }}
+
+# remove overlay of the session started by --session or by entering a project directory when the shell exits, keeping EXIT trap that is already set
+__kz_session_end() {
+  command kz session end >/dev/null 2>&1
+}
+
+__kz_trap_exit() {
+  eval "set -- $(trap -p EXIT)"
+  trap "__kz_session_end${3:+; $3}" EXIT
+}
+
+__kz_trap_exit
+{{- end }}
@@ -0,0 +1,47 @@
+# kz shell integration for fish
+# add `kz init fish | source` to ~/.config/fish/config.fish
+
+function kz
+    # environment changes only apply to current shell, skip them in command substitution
+    if status is-command-substitution
+        command kz $argv
+        return
+    end
+
+    set -l __kz_env_file (mktemp)
+    or return 1
+    env KZ_ENV_FILE=$__kz_env_file KZ_ENV_SHELL=fish kz $argv
+    set -l __kz_status $status
+    if test -s $__kz_env_file
+        source $__kz_env_file
+    end
+    rm -f $__kz_env_file
+    return $__kz_status
+end
+{{- if .PromptHook }}
+
+function __kz_hook_prompt --on-event fish_prompt
+    kz hook prompt
+end
+{{- end }}
+{{- if .ChpwdHook }}
+
+function __kz_hook_chpwd --on-variable PWD
+    kz hook chpwd
+end
+{{- end }}
+{{- if .Completion }}
+
+{{ .Completion }}
+{{- end }}
+{{- if .Session }}
+
+kz session start >/dev/null
+{{- end }}
+{{- if or .Session .ChpwdHook }}
+
+# remove overlay of the session started by --session or by entering a project directory when the shell exits
+function __kz_session_end --on-event fish_exit
+    command kz session end >/dev/null 2>&1
+end
+{{- end }}
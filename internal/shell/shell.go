@@ -0,0 +1,72 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type Shell string
+
+const (
+	Bash Shell = "bash"
+	Zsh  Shell = "zsh"
+	Fish Shell = "fish"
+)
+
+var Supported = []Shell{Bash, Zsh, Fish}
+
+func Parse(name string) (Shell, error) {
+	for _, s := range Supported {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported shell '%s', supported shells: %s", name, strings.Join(supportedNames(), ", "))
+}
+
+// Detect returns the shell from SHELL environment variable, falling back to bash when it's not supported
+func Detect() Shell {
+	s, err := Parse(filepath.Base(os.Getenv("SHELL")))
+	if err != nil {
+		return Bash
+	}
+
+	return s
+}
+
+// Export returns the statement to export an environment variable in the given shell
+func Export(s Shell, name string, value string) string {
+	if s == Fish {
+		return fmt.Sprintf("set -gx %s %s;", name, quoteFish(value))
+	}
+
+	return fmt.Sprintf("export %s=%s;", name, quotePosix(value))
+}
+
+// Unset returns the statement to remove an environment variable in the given shell
+func Unset(s Shell, name string) string {
+	if s == Fish {
+		return fmt.Sprintf("set -e %s;", name)
+	}
+
+	return fmt.Sprintf("unset %s;", name)
+}
+
+func quotePosix(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func quoteFish(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+}
+
+func supportedNames() []string {
+	var names []string
+	for _, s := range Supported {
+		names = append(names, string(s))
+	}
+	return names
+}
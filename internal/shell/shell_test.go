@@ -0,0 +1,34 @@
+//go:build unit
+
+package shell
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("return error when shell is not supported", func(t *testing.T) {
+		_, err := Parse("powershell")
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported shell 'powershell', supported shells: bash, zsh, fish")
+	})
+}
+
+func TestExport(t *testing.T) {
+	t.Run("quote value for posix shells", func(t *testing.T) {
+		require.Equal(t, `export KUBECONFIG='/path/it'\''s:/other';`, Export(Bash, "KUBECONFIG", "/path/it's:/other"))
+	})
+
+	t.Run("quote value for fish", func(t *testing.T) {
+		require.Equal(t, `set -gx KUBECONFIG '/path/it\'s:/other';`, Export(Fish, "KUBECONFIG", "/path/it's:/other"))
+	})
+}
+
+func TestUnset(t *testing.T) {
+	t.Run("return unset statement for given shell", func(t *testing.T) {
+		require.Equal(t, "unset KUBECONFIG;", Unset(Zsh, "KUBECONFIG"))
+		require.Equal(t, "set -e KUBECONFIG;", Unset(Fish, "KUBECONFIG"))
+	})
+}
@@ -1,6 +1,10 @@
 package tui
 
-import "github.com/pterm/pterm"
+import (
+	"github.com/pterm/pterm"
+	"golang.org/x/term"
+	"os"
+)
 
 func ShowDropdown(label string, options []string) (string, error) {
 	return pterm.DefaultInteractiveSelect.
@@ -8,3 +12,8 @@ func ShowDropdown(label string, options []string) (string, error) {
 		WithOptions(options).
 		Show()
 }
+
+// IsInteractive returns true when standard input is a terminal, i.e. a user is there to answer prompts
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}